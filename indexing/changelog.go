@@ -0,0 +1,235 @@
+package indexing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OpChange describes a single operation that was added, removed or changed.
+type OpChange struct {
+	Method      string   `json:"method"`
+	Template    string   `json:"template"`
+	OperationID string   `json:"operationId"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// ChangeEntry is one changelog record produced when a spec's content hash changes.
+type ChangeEntry struct {
+	SpecName string     `json:"specName"`
+	Time     time.Time  `json:"time"`
+	PrevHash string     `json:"prevHash"`
+	Hash     string     `json:"hash"`
+	Added    []OpChange `json:"added,omitempty"`
+	Removed  []OpChange `json:"removed,omitempty"`
+	Changed  []OpChange `json:"changed,omitempty"`
+}
+
+// Empty reports whether the entry records no operation-level changes.
+func (c ChangeEntry) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Changed) == 0
+}
+
+// Summary renders a one-line human-readable description of the entry.
+func (c ChangeEntry) Summary() string {
+	return fmt.Sprintf("%s: %d added, %d removed, %d changed",
+		c.SpecName, len(c.Added), len(c.Removed), len(c.Changed))
+}
+
+// Text renders the entry as a human-readable list of operations.
+func (c ChangeEntry) Text() string {
+	var out strings.Builder
+	write := func(prefix string, ops []OpChange) {
+		for _, op := range ops {
+			fmt.Fprintf(&out, "%s %s %s", prefix, op.Method, op.Template)
+			if op.OperationID != "" {
+				fmt.Fprintf(&out, " (%s)", op.OperationID)
+			}
+			out.WriteString("\n")
+		}
+	}
+	write("+", c.Added)
+	write("-", c.Removed)
+	write("~", c.Changed)
+	return out.String()
+}
+
+// FilterTags keeps only the operations carrying at least one of the tags.
+func (c ChangeEntry) FilterTags(tags []string) ChangeEntry {
+	if len(tags) == 0 {
+		return c
+	}
+	keep := func(ops []OpChange) []OpChange {
+		var out []OpChange
+		for _, op := range ops {
+			if hasAnyTag(op.Tags, tags) {
+				out = append(out, op)
+			}
+		}
+		return out
+	}
+	c.Added = keep(c.Added)
+	c.Removed = keep(c.Removed)
+	c.Changed = keep(c.Changed)
+	return c
+}
+
+func hasAnyTag(have, want []string) bool {
+	for _, h := range have {
+		for _, w := range want {
+			if h == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func opsSnapshotPath(baseDir, specName string) string {
+	return filepath.Join(baseDir, specName+".ops.json")
+}
+
+func changelogPath(baseDir, specName string) string {
+	return filepath.Join(baseDir, specName+".changes.json")
+}
+
+func opKey(e OpEntry) string {
+	return e.Method + " " + e.Template
+}
+
+func toOpChange(e OpEntry) OpChange {
+	return OpChange{e.Method, e.Template, e.OperationID, e.Tags}
+}
+
+// DiffOpEntries compares two operation snapshots keyed by method and template.
+func DiffOpEntries(prev, curr []OpEntry) (added, removed, changed []OpChange) {
+	old := make(map[string]OpEntry, len(prev))
+	for _, e := range prev {
+		old[opKey(e)] = e
+	}
+	seen := make(map[string]struct{}, len(curr))
+	for _, e := range curr {
+		k := opKey(e)
+		seen[k] = struct{}{}
+		p, ok := old[k]
+		switch {
+		case !ok:
+			added = append(added, toOpChange(e))
+		case p.Hash != e.Hash:
+			changed = append(changed, toOpChange(e))
+		}
+	}
+	for _, e := range prev {
+		if _, ok := seen[opKey(e)]; !ok {
+			removed = append(removed, toOpChange(e))
+		}
+	}
+	sortOpChanges(added)
+	sortOpChanges(removed)
+	sortOpChanges(changed)
+	return added, removed, changed
+}
+
+func sortOpChanges(ops []OpChange) {
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].Template != ops[j].Template {
+			return ops[i].Template < ops[j].Template
+		}
+		return ops[i].Method < ops[j].Method
+	})
+}
+
+// recordChanges diffs the new operations against the last snapshot, appends a
+// changelog entry when anything differs and stores the new snapshot.
+func recordChanges(baseDir string, spec *SpecIndex, prevHash string, entries []OpEntry) error {
+	snapPath := opsSnapshotPath(baseDir, spec.SpecName)
+
+	var prev []OpEntry
+	prevErr := readJSONFile(snapPath, &prev)
+
+	if prevErr == nil && prevHash != "" {
+		added, removed, changed := DiffOpEntries(prev, entries)
+		entry := ChangeEntry{
+			SpecName: spec.SpecName,
+			Time:     time.Now().UTC(),
+			PrevHash: prevHash,
+			Hash:     spec.ContentHash,
+			Added:    added,
+			Removed:  removed,
+			Changed:  changed,
+		}
+		if !entry.Empty() {
+			if err := appendChangelog(baseDir, entry); err != nil {
+				return err
+			}
+		}
+	}
+
+	return writeOpsSnapshot(snapPath, entries)
+}
+
+// ensureOpsSnapshot writes a baseline snapshot for an index that was built
+// before snapshots existed, so its next content change is diffed.
+func ensureOpsSnapshot(baseDir string, spec *SpecIndex) error {
+	snapPath := opsSnapshotPath(baseDir, spec.SpecName)
+	if _, err := os.Stat(snapPath); !os.IsNotExist(err) {
+		return err
+	}
+	doc, err := LoadSpecDocument(spec.File)
+	if err != nil {
+		return err
+	}
+	return writeOpsSnapshot(snapPath, extractOpEntries(doc))
+}
+
+func writeOpsSnapshot(snapPath string, entries []OpEntry) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(snapPath, data, 0o644); err != nil {
+		return fmt.Errorf("write ops snapshot %q: %w", snapPath, err)
+	}
+	return nil
+}
+
+func appendChangelog(baseDir string, entry ChangeEntry) error {
+	path := changelogPath(baseDir, entry.SpecName)
+	var entries []ChangeEntry
+	if err := readJSONFile(path, &entries); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read changelog %q: %w", path, err)
+	}
+	entries = append(entries, entry)
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("write changelog %q: %w", path, err)
+	}
+	return nil
+}
+
+// LoadChangelog reads every spec changelog under baseDir, newest first.
+func LoadChangelog(baseDir string) ([]ChangeEntry, error) {
+	files, err := filepath.Glob(filepath.Join(baseDir, "*.changes.json"))
+	if err != nil {
+		return nil, err
+	}
+	var all []ChangeEntry
+	for _, f := range files {
+		var entries []ChangeEntry
+		if err := readJSONFile(f, &entries); err != nil {
+			return nil, fmt.Errorf("read changelog %q: %w", f, err)
+		}
+		all = append(all, entries...)
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].Time.After(all[j].Time)
+	})
+	return all, nil
+}
@@ -0,0 +1,99 @@
+package indexing_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"better-docs/indexing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangelogRecordedOnHashChange(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	v1 := `{
+	  "openapi": "3.0.0",
+	  "info": { "title": "Pets", "version": "1.0.0" },
+	  "servers": [{ "url": "http://pets.test/api" }],
+	  "paths": {
+	    "/pets": {
+	      "get": { "operationId": "listPets", "tags": ["pets"], "responses": { "200": { "description": "OK" } } }
+	    },
+	    "/pets/{id}": {
+	      "get": { "operationId": "getPet", "tags": ["pets"], "responses": { "200": { "description": "OK" } } },
+	      "delete": { "operationId": "deletePet", "tags": ["admin"], "responses": { "204": { "description": "Gone" } } }
+	    }
+	  }
+	}`
+	v2 := `{
+	  "openapi": "3.0.0",
+	  "info": { "title": "Pets", "version": "1.1.0" },
+	  "servers": [{ "url": "http://pets.test/api" }],
+	  "paths": {
+	    "/pets": {
+	      "get": { "operationId": "listPets", "tags": ["pets"], "summary": "List pets", "responses": { "200": { "description": "OK" } } },
+	      "post": { "operationId": "createPet", "tags": ["pets"], "responses": { "201": { "description": "Created" } } }
+	    },
+	    "/pets/{id}": {
+	      "get": { "operationId": "getPet", "tags": ["pets"], "responses": { "200": { "description": "OK" } } }
+	    }
+	  }
+	}`
+
+	specPath := writeFile(t, tmpDir, "pets.json", v1)
+	cfg := []indexing.SpecConfig{{DisplayName: "Pets", Name: "pets", File: specPath}}
+	cfgBytes, err := json.Marshal(cfg)
+	require.NoError(t, err)
+	cfgPath := writeFile(t, tmpDir, "specs.json", string(cfgBytes))
+	cachePath := filepath.Join(tmpDir, "cache.gob")
+	idxDir := filepath.Join(tmpDir, "bleve_indexes")
+
+	build := func() {
+		reg, err := indexing.LoadConfigAndIndex(context.Background(), cfgPath, cachePath)
+		require.NoError(t, err)
+		idx, err := indexing.BuildOrOpenSpecIndex(idxDir, indexing.NewIndexMapping(), reg["pets.test"])
+		require.NoError(t, err)
+		require.NoError(t, idx.Close())
+	}
+
+	// First build has nothing to compare against.
+	build()
+	changes, err := indexing.LoadChangelog(idxDir)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	// Unchanged content does not produce an entry.
+	build()
+	changes, err = indexing.LoadChangelog(idxDir)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	// An index built before snapshots existed gets a baseline when opened.
+	require.NoError(t, os.Remove(filepath.Join(idxDir, "pets.ops.json")))
+	build()
+	require.FileExists(t, filepath.Join(idxDir, "pets.ops.json"))
+
+	writeFile(t, tmpDir, "pets.json", v2)
+	build()
+
+	changes, err = indexing.LoadChangelog(idxDir)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+
+	c := changes[0]
+	require.Equal(t, "pets", c.SpecName)
+	require.Len(t, c.Added, 1)
+	require.Equal(t, "createPet", c.Added[0].OperationID)
+	require.Len(t, c.Removed, 1)
+	require.Equal(t, "deletePet", c.Removed[0].OperationID)
+	require.Len(t, c.Changed, 1)
+	require.Equal(t, "listPets", c.Changed[0].OperationID)
+
+	admin := c.FilterTags([]string{"admin"})
+	require.Empty(t, admin.Added)
+	require.Empty(t, admin.Changed)
+	require.Len(t, admin.Removed, 1)
+}
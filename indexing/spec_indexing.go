@@ -12,6 +12,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
@@ -37,6 +38,7 @@ type OpEntry struct {
 	OperationID string
 	Description string
 	Tags        []string
+	Hash        string
 }
 
 // SpecIndex holds metadata needed at runtime for one spec.
@@ -46,6 +48,7 @@ type SpecIndex struct {
 	Host        string
 	BasePath    string
 	ContentHash string
+	Servers     []string
 }
 
 // SpecBuild is an in-memory build artifact
@@ -59,6 +62,15 @@ type SpecBuild struct {
 // Registry maps hostnames to their loaded SpecIndex.
 type Registry map[string]*SpecIndex
 
+// ForURL returns the spec served on u's host, or nil. Keys keep the server's
+// port when it declares one, so host:port is tried before the bare host.
+func (reg Registry) ForURL(u *url.URL) *SpecIndex {
+	if meta, ok := reg[u.Host]; ok {
+		return meta
+	}
+	return reg[u.Hostname()]
+}
+
 type SearchResult struct {
 	SpecName    string
 	OperationID string
@@ -118,6 +130,20 @@ func firstServerInfo(raw map[string]interface{}) (host, basePath string, err err
 	return host, basePath, nil
 }
 
+// serverURLs lists every servers[].url declared by the spec.
+func serverURLs(raw map[string]interface{}) []string {
+	sv, _ := raw["servers"].([]interface{})
+	out := make([]string, 0, len(sv))
+	for _, s := range sv {
+		if m, ok := s.(map[string]interface{}); ok {
+			if u, ok := m["url"].(string); ok && u != "" {
+				out = append(out, u)
+			}
+		}
+	}
+	return out
+}
+
 func LoadConfigAndIndex(ctx context.Context, configPath, cachePath string) (Registry, error) {
 	var cfgs []SpecConfig
 	if err := readJSONFile(configPath, &cfgs); err != nil {
@@ -153,7 +179,7 @@ func LoadConfigAndIndex(ctx context.Context, configPath, cachePath string) (Regi
 		if err != nil {
 			return nil, fmt.Errorf("%s: %w", cfg.Name, err)
 		}
-		registry[host] = &SpecIndex{cfg.Name, abs, host, base, hash}
+		registry[host] = &SpecIndex{cfg.Name, abs, host, base, hash, serverURLs(raw)}
 	}
 
 	if f, err := os.Create(cachePath); err == nil {
@@ -263,11 +289,16 @@ func BuildOrOpenSpecIndex(baseDir string, im IndexMapping, spec *SpecIndex) (ble
 			return nil, fmt.Errorf("create index %q: %w", dir, err)
 		}
 
-		if err := indexSpecOnDisk(idx, spec); err != nil {
+		entries, err := indexSpecOnDisk(idx, spec)
+		if err != nil {
 			idx.Close()
 			return nil, err
 		}
 
+		if err := recordChanges(baseDir, spec, prevHash, entries); err != nil {
+			log.Printf("changelog for %s: %v", spec.SpecName, err)
+		}
+
 		if err := os.WriteFile(hashFile, []byte(spec.ContentHash), 0o644); err != nil {
 			idx.Close()
 			return nil, fmt.Errorf("write hash %q: %w", hashFile, err)
@@ -280,6 +311,9 @@ func BuildOrOpenSpecIndex(baseDir string, im IndexMapping, spec *SpecIndex) (ble
 	if err != nil {
 		return nil, fmt.Errorf("open index %q: %w", dir, err)
 	}
+	if err := ensureOpsSnapshot(baseDir, spec); err != nil {
+		log.Printf("changelog for %s: %v", spec.SpecName, err)
+	}
 	return idx, nil
 }
 
@@ -290,10 +324,13 @@ func FindOperation(idx bleve.Index, reg Registry, method, rawURL string) (string
 		return "", "", nil, fmt.Errorf("invalid URL %q: %w", rawURL, err)
 	}
 
-	host := u.Hostname()
-	meta, ok := reg[host]
-	if !ok {
-		return "", "", nil, fmt.Errorf("no spec for host %q", host)
+	if u.Host == "" {
+		return findRelative(idx, reg, method, u)
+	}
+
+	meta := reg.ForURL(u)
+	if meta == nil {
+		return "", "", nil, fmt.Errorf("no spec for host %q", u.Hostname())
 	}
 
 	base := strings.TrimRight(meta.BasePath, "/")
@@ -339,6 +376,47 @@ func FindOperation(idx bleve.Index, reg Registry, method, rawURL string) (string
 	return "", "", nil, fmt.Errorf("no operation found for %s %s in spec %q", method, rel, meta.SpecName)
 }
 
+// findRelative looks a path-only URL, as logged by MockMvc, up in every spec
+// in turn, ordered by host so the result is stable.
+func findRelative(idx bleve.Index, reg Registry, method string, u *url.URL) (string, string, map[string]string, error) {
+	hosts := make([]string, 0, len(reg))
+	for h := range reg {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+	for _, h := range hosts {
+		abs := *u
+		abs.Scheme, abs.Host = "http", h
+		if spec, op, params, err := FindOperation(idx, reg, method, abs.String()); err == nil {
+			return spec, op, params, nil
+		}
+	}
+	return "", "", nil, fmt.Errorf("no operation found for %s %s in any spec", method, u.Path)
+}
+
+// ResolveURL makes a path-only URL absolute against the first server of the
+// named spec. Absolute URLs are returned unchanged.
+func ResolveURL(reg Registry, spec, rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host != "" {
+		return rawURL
+	}
+	for _, meta := range reg {
+		if meta.SpecName != spec {
+			continue
+		}
+		scheme := "http"
+		if len(meta.Servers) > 0 {
+			if su, err := url.Parse(meta.Servers[0]); err == nil && su.Scheme != "" {
+				scheme = su.Scheme
+			}
+		}
+		u.Scheme, u.Host = scheme, meta.Host
+		return u.String()
+	}
+	return rawURL
+}
+
 // SearchBleve performs a full-text search with optional filters and paging.
 func SearchBleve(idx bleve.Index, specNames, tagFilters []string, queryStr string, limit, offset int) ([]SearchResult, uint64, error) {
 	conj := []query.Query{bleve.NewQueryStringQuery(queryStr)}
@@ -396,21 +474,33 @@ func ifaceSliceToString(in interface{}) []string {
 	}
 }
 
-func indexSpecOnDisk(idx bleve.Index, spec *SpecIndex) error {
-	data, err := os.ReadFile(spec.File)
+// LoadSpecDocument reads a spec file and loads it with the same sanitizing
+// applied at index time, so lookups agree with the indexed operations.
+func LoadSpecDocument(file string) (*openapi3.T, error) {
+	data, err := os.ReadFile(file)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var raw map[string]interface{}
-	_ = json.Unmarshal(data, &raw)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing spec %q: %w", file, err)
+	}
 	sanitizePaths(raw)
 	sanitizeComponents(raw)
 	injectMissingSchemas(raw)
 	fixed, _ := json.Marshal(raw)
 
-	doc, _ := openapi3.NewLoader().LoadFromData(fixed)
-	for _, e := range extractOpEntries(doc) {
+	return openapi3.NewLoader().LoadFromData(fixed)
+}
+
+func indexSpecOnDisk(idx bleve.Index, spec *SpecIndex) ([]OpEntry, error) {
+	doc, err := LoadSpecDocument(spec.File)
+	if err != nil {
+		return nil, err
+	}
+	entries := extractOpEntries(doc)
+	for _, e := range entries {
 		docMap := map[string]interface{}{
 			"SpecName":    spec.SpecName,
 			"OperationID": e.OperationID,
@@ -421,11 +511,11 @@ func indexSpecOnDisk(idx bleve.Index, spec *SpecIndex) error {
 		}
 		id := fmt.Sprintf("%s|%s|%s|%s", spec.SpecName, e.Method, e.Template, e.OperationID)
 		if err := idx.Index(id, docMap); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	return nil
+	return entries, nil
 }
 
 // sanitizePaths normalizes HTTP verbs and removes unknown entries.
@@ -514,7 +604,11 @@ func extractOpEntries(doc *openapi3.T) []OpEntry {
 			if desc == "" {
 				desc = op.Description
 			}
-			entries = append(entries, OpEntry{method, tmpl, op.OperationID, desc, op.Tags})
+			var hash string
+			if b, err := json.Marshal(op); err == nil {
+				hash = computeSHA(b)
+			}
+			entries = append(entries, OpEntry{method, tmpl, op.OperationID, desc, op.Tags, hash})
 		}
 	}
 	return entries
@@ -571,6 +665,15 @@ func matchTemplate(tmpl, path string) bool {
 	return true
 }
 
+// MatchPath matches a path relative to the server base against a template
+// and returns the extracted path parameters.
+func MatchPath(tmpl, path string) (map[string]string, bool) {
+	if !matchTemplate(tmpl, path) {
+		return nil, false
+	}
+	return extractPathParams("", tmpl, path), true
+}
+
 func extractPathParams(basePath, tmpl, fullPath string) map[string]string {
 	clean := strings.TrimPrefix(fullPath, basePath)
 	clean = strings.TrimPrefix(clean, "/")
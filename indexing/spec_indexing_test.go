@@ -3,6 +3,7 @@ package indexing_test
 import (
 	"context"
 	"encoding/json"
+	"net/url"
 	"os"
 	"path/filepath"
 	"testing"
@@ -69,6 +70,14 @@ func TestLoadConfigAndFindOperation(t *testing.T) {
 	// Unknown path
 	_, _, _, err = indexing.FindOperation(idx, reg, "GET", "http://example.com/api/unknown/1")
 	require.Error(t, err)
+
+	// Path-only URL, as MockMvc logs it
+	specName, opID, params, err := indexing.FindOperation(idx, reg, "GET", "/api/items/7")
+	require.NoError(t, err)
+	require.Equal(t, "test-spec", specName)
+	require.Equal(t, "getItem", opID)
+	require.Equal(t, map[string]string{"id": "7"}, params)
+	require.Equal(t, "http://example.com/api/items/7", indexing.ResolveURL(reg, specName, "/api/items/7"))
 }
 
 func TestBleveSearch(t *testing.T) {
@@ -143,3 +152,25 @@ func TestBleveSearch(t *testing.T) {
 	require.Equal(t, uint64(0), total)
 	require.Len(t, results, 0)
 }
+
+func TestRegistryForURL(t *testing.T) {
+	reg := indexing.Registry{
+		"localhost:8080": {SpecName: "pets"},
+		"example.com":    {SpecName: "orders"},
+	}
+	for raw, want := range map[string]string{
+		"http://localhost:8080/pets":   "pets",
+		"https://example.com/orders":   "orders",
+		"https://example.com:443/x":    "orders",
+		"http://localhost:9090/pets":   "",
+		"http://unknown.example.com/x": "",
+	} {
+		u, err := url.Parse(raw)
+		require.NoError(t, err)
+		got := ""
+		if meta := reg.ForURL(u); meta != nil {
+			got = meta.SpecName
+		}
+		require.Equal(t, want, got, raw)
+	}
+}
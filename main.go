@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"regexp"
 	"time"
 
 	"better-docs/indexing"
@@ -22,6 +23,15 @@ var (
 	listenPort int
 	timeout    time.Duration
 	cacheDir   string
+
+	proxyAllow        []string
+	proxyAllowPrivate bool
+	corsOrigins       []string
+	secretsFile       string
+	validateMode      string
+	mockStateful      bool
+	recordDir         string
+	raLogPrefix       string
 )
 
 func run(cmd *cobra.Command, args []string) error {
@@ -43,16 +53,71 @@ func run(cmd *cobra.Command, args []string) error {
 	}
 
 	svc := route.NewSearchService(reg, idx)
-	ac := route.NewActionService(reg, idx)
+	if raLogPrefix != "" {
+		if svc.LogPrefix, err = regexp.Compile(raLogPrefix); err != nil {
+			return fmt.Errorf("invalid --ra-log-prefix: %w", err)
+		}
+	}
+	cs := route.NewChangesService(cacheDir)
 
-	specs, proxyMap, err := route.LoadSpecs(specFile)
+	specs, upstreams, err := route.LoadSpecs(specFile)
 	if err != nil {
 		return fmt.Errorf("failed to load specs: %w", err)
 	}
 
-	httpClient := &http.Client{Timeout: timeout}
+	guard, err := route.NewGuard(route.GuardConfig{
+		AllowHosts:   proxyAllow,
+		AllowPrivate: proxyAllowPrivate,
+	}, route.AllowedHosts(specs, reg))
+	if err != nil {
+		return fmt.Errorf("failed to build proxy allowlist: %w", err)
+	}
+
+	hostSpecs := route.SpecHosts(specs, reg)
+	proxyTransport, err := route.NewSpecTransport(guard.Transport(), specs, hostSpecs)
+	if err != nil {
+		return fmt.Errorf("failed to configure upstream TLS: %w", err)
+	}
+	httpClient := &http.Client{
+		Timeout:       timeout,
+		Transport:     proxyTransport,
+		CheckRedirect: guard.CheckRedirect,
+	}
+
+	upstreams.StartHealthChecks(ctx, httpClient)
+
+	secrets, err := route.LoadSecretStore(secretsFile)
+	if err != nil {
+		return fmt.Errorf("failed to load secrets: %w", err)
+	}
+	tokenClient := &http.Client{Timeout: timeout}
+	if err := upstreams.LoadCredentials(secrets, tokenClient); err != nil {
+		return fmt.Errorf("failed to load credentials: %w", err)
+	}
+	sessions := route.NewSessionStore(tokenClient)
+	oa := route.NewOAuthService(specs, secrets, tokenClient, sessions)
+
+	docs := route.NewDocuments(specs)
+	validator, err := route.NewValidator(validateMode, docs)
+	if err != nil {
+		return fmt.Errorf("failed to configure validation: %w", err)
+	}
+
+	ms := route.NewMockService(docs, mockStateful)
+
+	faults := route.NewFaultInjector(docs)
+	recorder, err := route.NewRecorder(recordDir, idx, reg, docs)
+	if err != nil {
+		return fmt.Errorf("failed to set up recording: %w", err)
+	}
+
+	ps := route.NewProxyService(upstreams, hostSpecs, httpClient, guard, sessions, validator, faults, recorder)
+	ps.Origins = corsOrigins
+	ac := route.NewActionService(reg, idx, ps)
+	ac.LogPrefix = svc.LogPrefix
+
 	mux := http.NewServeMux()
-	route.RegisterRoutes(mux, specs, proxyMap, httpClient, staticDir, indexFile, svc, ac)
+	route.RegisterRoutes(mux, specs, ps, corsOrigins, staticDir, indexFile, svc, ac, cs, oa, ms)
 
 	addr := fmt.Sprintf("%s:%d", listenHost, listenPort)
 	log.Printf("Listening on http://%s", addr)
@@ -73,6 +138,14 @@ func main() {
 	root.Flags().IntVar(&listenPort, "port", 5001, "listen port")
 	root.Flags().DurationVar(&timeout, "timeout", 30*time.Second, "timeout for proxied requests")
 	root.Flags().StringVar(&cacheDir, "cache", ".bleveIndexes", "path to indexing cache file")
+	root.Flags().StringSliceVar(&proxyAllow, "proxy-allow", nil, "extra hosts, *.suffix wildcards or CIDRs the ?url= proxy may reach")
+	root.Flags().BoolVar(&proxyAllowPrivate, "proxy-allow-private", false, "allow wildcard-matched proxy targets to resolve to private, loopback or link-local addresses")
+	root.Flags().StringVar(&secretsFile, "secrets", "", "path to a JSON file of secret name → value used by spec auth")
+	root.Flags().StringVar(&validateMode, "validate", "off", "validate proxied traffic against the spec: off, report or enforce")
+	root.Flags().BoolVar(&mockStateful, "mock-stateful", false, "keep resources created through /mock/ in memory so CRUD calls see each other")
+	root.Flags().StringVar(&recordDir, "record", "", "directory to record proxied traffic to (disabled when empty)")
+	root.Flags().StringVar(&raLogPrefix, "ra-log-prefix", "", "regex for the logger prefix to strip from RestAssured log lines (default: common timestamp/thread/level patterns)")
+	root.Flags().StringSliceVar(&corsOrigins, "cors-origin", nil, "origins allowed to call the proxy cross-origin (\"*\" for any)")
 
 	if err := root.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
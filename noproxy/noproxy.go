@@ -0,0 +1,56 @@
+// Package noproxy matches URLs against no_proxy entries. It is shared by the
+// server's outbound proxies and swagger-fetcher's downloads so both read a
+// NoProxy list the same way.
+package noproxy
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Match reports whether target matches a no_proxy entry: "*", an exact host,
+// a domain suffix (with or without a leading "." or "*."), an IP or a CIDR,
+// each host or IP with an optional ":port".
+func Match(entries []string, target *url.URL) bool {
+	host := strings.ToLower(target.Hostname())
+	port := target.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443", "ws": "80", "wss": "443"}[target.Scheme]
+	}
+	ip := net.ParseIP(host)
+
+	for _, e := range entries {
+		e = strings.ToLower(strings.TrimSpace(e))
+		switch {
+		case e == "":
+			continue
+		case e == "*":
+			return true
+		case strings.Contains(e, "/"):
+			if _, n, err := net.ParseCIDR(e); err == nil && ip != nil && n.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		ehost, eport := e, ""
+		if h, p, err := net.SplitHostPort(e); err == nil {
+			ehost, eport = h, p
+		}
+		if eport != "" && eport != port {
+			continue
+		}
+		if eip := net.ParseIP(ehost); eip != nil {
+			if ip != nil && eip.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		ehost = strings.TrimPrefix(strings.TrimPrefix(ehost, "*"), ".")
+		if host == ehost || strings.HasSuffix(host, "."+ehost) {
+			return true
+		}
+	}
+	return false
+}
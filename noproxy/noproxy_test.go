@@ -0,0 +1,35 @@
+package noproxy
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	entries := []string{"internal.example.com", ".corp.test", "10.0.0.0/8", "192.168.1.5", "api.test:8443", "*.svc.test", "ws.test:80"}
+	cases := map[string]bool{
+		"http://internal.example.com/x":   true,
+		"http://a.internal.example.com/x": true,
+		"http://example.com/x":            false,
+		"https://svc.corp.test/x":         true,
+		"http://corp.test/x":              true,
+		"http://10.1.2.3/x":               true,
+		"http://192.168.1.5:8080/x":       true,
+		"http://192.168.1.6/x":            false,
+		"https://api.test:8443/x":         true,
+		"https://api.test/x":              false,
+		"http://a.svc.test/x":             true,
+		"ws://ws.test/socket":             true,
+		"wss://ws.test/socket":            false,
+	}
+	for raw, want := range cases {
+		u, err := url.Parse(raw)
+		require.NoError(t, err)
+		require.Equal(t, want, Match(entries, u), raw)
+	}
+	u, _ := url.Parse("http://anything.test")
+	require.True(t, Match([]string{"*"}, u))
+	require.False(t, Match(nil, u))
+}
@@ -0,0 +1,418 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// curlArgFlags are the curl options that take a value; any other option is
+// treated as a switch. Options this parser does not act on are skipped with
+// their value.
+var curlArgFlags = map[string]bool{
+	"-X": true, "--request": true,
+	"-H": true, "--header": true,
+	"-d": true, "--data": true, "--data-ascii": true, "--data-raw": true,
+	"--data-binary": true, "--data-urlencode": true, "--json": true,
+	"-F": true, "--form": true, "--form-string": true,
+	"-u": true, "--user": true,
+	"-A": true, "--user-agent": true,
+	"-e": true, "--referer": true,
+	"-b": true, "--cookie": true,
+	"-x": true, "--proxy": true, "--url": true,
+	"-o": true, "--output": true, "-w": true, "--write-out": true,
+	"-m": true, "--max-time": true, "--connect-timeout": true,
+	"--retry": true, "--retry-delay": true, "--retry-max-time": true,
+	"-c": true, "--cookie-jar": true, "-T": true, "--upload-file": true,
+	"-E": true, "--cert": true, "--key": true, "--cacert": true, "--capath": true,
+	"--resolve": true, "--connect-to": true, "--limit-rate": true,
+	"-r": true, "--range": true, "-K": true, "--config": true,
+	"--proxy-user": true, "-U": true, "--interface": true,
+}
+
+// IsCurl reports whether input looks like a curl command line, optionally
+// behind a "$ " shell prompt.
+func IsCurl(input string) bool {
+	s := strings.TrimSpace(input)
+	s = strings.TrimSpace(strings.TrimPrefix(s, "$"))
+	fields := strings.Fields(s)
+	return len(fields) > 0 && (fields[0] == "curl" || fields[0] == "curl.exe")
+}
+
+// ParseCurl parses a curl command line as pasted from a terminal or a
+// browser's "Copy as cURL". It understands the options that shape the request
+// (-X, -H, -d and its --data-* variants, -F, -u, -G, -I, -b, -A, -e, -x and
+// --url) and bash quoting, including $'...' strings and backslash line
+// continuations. Values read from files (@file) cannot be reproduced and are
+// rejected for data, or sent empty for form uploads.
+func ParseCurl(input string) (ParsedRequest, error) {
+	pr := ParsedRequest{Headers: textproto.MIMEHeader{}, Params: url.Values{}}
+
+	s := strings.TrimSpace(input)
+	s = strings.TrimSpace(strings.TrimPrefix(s, "$"))
+	args, err := splitShellWords(s)
+	if err != nil {
+		return pr, err
+	}
+	if len(args) == 0 || (args[0] != "curl" && args[0] != "curl.exe") {
+		return pr, fmt.Errorf("not a curl command")
+	}
+
+	var (
+		data    []string
+		get     bool
+		head    bool
+		forms   []curlFormField
+		rawURL  string
+		hasData bool
+	)
+
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if arg == "" || arg[0] != '-' || arg == "-" {
+			if rawURL == "" {
+				rawURL = arg
+			}
+			continue
+		}
+
+		// split "-XPOST" and "-sSL" style short options
+		flag, value, hasValue := arg, "", false
+		if !strings.HasPrefix(arg, "--") && len(arg) > 2 {
+			for j := 1; j < len(arg); j++ {
+				f := "-" + arg[j:j+1]
+				if curlArgFlags[f] {
+					flag, value, hasValue = f, arg[j+1:], j+1 < len(arg)
+					break
+				}
+				if j == len(arg)-1 {
+					flag = f
+				} else {
+					applyCurlSwitch(f, &get, &head)
+				}
+			}
+		}
+		if curlArgFlags[flag] && !hasValue {
+			if i+1 >= len(args) {
+				return pr, fmt.Errorf("curl: option %s needs a value", flag)
+			}
+			i++
+			value = args[i]
+		}
+
+		switch flag {
+		case "-X", "--request":
+			pr.Method = strings.ToUpper(value)
+		case "-H", "--header":
+			name, v, ok := strings.Cut(value, ":")
+			if !ok {
+				continue // "Name;" sends an empty header, which we cannot express
+			}
+			pr.Headers.Add(textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name)), strings.TrimSpace(v))
+		case "-d", "--data", "--data-ascii", "--data-binary":
+			if strings.HasPrefix(value, "@") {
+				return pr, fmt.Errorf("curl: %s reads %s, which is not available here", flag, value[1:])
+			}
+			if flag != "--data-binary" {
+				value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+			}
+			data, hasData = append(data, value), true
+		case "--data-raw":
+			data, hasData = append(data, value), true
+		case "--json":
+			data, hasData = append(data, value), true
+			if pr.Headers.Get("Content-Type") == "" {
+				pr.Headers.Set("Content-Type", "application/json")
+			}
+			if pr.Headers.Get("Accept") == "" {
+				pr.Headers.Set("Accept", "application/json")
+			}
+		case "--data-urlencode":
+			data, hasData = append(data, urlencodeCurlData(value)), true
+		case "-F", "--form":
+			forms = append(forms, curlFormField{spec: value})
+		case "--form-string":
+			forms = append(forms, curlFormField{spec: value, literal: true})
+		case "-u", "--user":
+			pr.Headers.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(value)))
+		case "-A", "--user-agent":
+			pr.Headers.Set("User-Agent", value)
+		case "-e", "--referer":
+			pr.Headers.Set("Referer", value)
+		case "-b", "--cookie":
+			if !strings.Contains(value, "=") {
+				continue // a cookie file
+			}
+			pr.Headers.Add("Cookie", value)
+			for _, c := range strings.Split(value, ";") {
+				name, v, _ := strings.Cut(strings.TrimSpace(c), "=")
+				pr.Cookies = append(pr.Cookies, Param{Name: name, Value: v})
+			}
+		case "-x", "--proxy":
+			pr.Proxy = value
+		case "--url":
+			rawURL = value
+		default:
+			if !curlArgFlags[flag] {
+				applyCurlSwitch(flag, &get, &head)
+			}
+		}
+	}
+
+	if rawURL == "" {
+		return pr, fmt.Errorf("curl: no URL")
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return pr, fmt.Errorf("curl: invalid URL: %w", err)
+	}
+
+	body := strings.Join(data, "&")
+	switch {
+	case get && hasData:
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += body
+		hasData = false
+	case hasData:
+		pr.Body = []byte(body)
+		if pr.Headers.Get("Content-Type") == "" {
+			pr.Headers.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	case len(forms) > 0:
+		if err := buildCurlForm(&pr, forms); err != nil {
+			return pr, err
+		}
+	}
+
+	if pr.Method == "" {
+		switch {
+		case head:
+			pr.Method = "HEAD"
+		case hasData || len(forms) > 0:
+			pr.Method = "POST"
+		default:
+			pr.Method = "GET"
+		}
+	}
+	pr.URI = u.String()
+	pr.Params = u.Query()
+	return pr, nil
+}
+
+// applyCurlSwitch records the value-less options that change the request.
+func applyCurlSwitch(flag string, get, head *bool) {
+	switch flag {
+	case "-G", "--get":
+		*get = true
+	case "-I", "--head":
+		*head = true
+	}
+}
+
+// urlencodeCurlData applies --data-urlencode's "content", "=content" and
+// "name=content" forms.
+func urlencodeCurlData(v string) string {
+	name, content, ok := strings.Cut(v, "=")
+	if !ok {
+		return url.QueryEscape(v)
+	}
+	if name == "" {
+		return url.QueryEscape(content)
+	}
+	return name + "=" + url.QueryEscape(content)
+}
+
+// curlFormField is one -F or --form-string value.
+type curlFormField struct {
+	spec    string
+	literal bool
+}
+
+// buildCurlForm turns -F values into Multiparts and a multipart body.
+// A value starting with @ or < names a file; ;type= and ;filename= set the
+// part's content type and file name.
+func buildCurlForm(pr *ParsedRequest, forms []curlFormField) error {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, f := range forms {
+		name, value, ok := strings.Cut(f.spec, "=")
+		if !ok {
+			return fmt.Errorf("curl: invalid form field %q", f.spec)
+		}
+		mp := Multipart{Disposition: "form-data", ControlName: name}
+
+		if f.literal {
+			mp.Content = value
+		} else {
+			fields := strings.Split(value, ";")
+			value = fields[0]
+			for _, attr := range fields[1:] {
+				k, v, _ := strings.Cut(strings.TrimSpace(attr), "=")
+				switch k {
+				case "type":
+					mp.MimeType = v
+				case "filename":
+					mp.FileName = v
+				}
+			}
+			switch {
+			case strings.HasPrefix(value, "@"):
+				if mp.FileName == "" {
+					mp.FileName = path.Base(value[1:])
+				}
+				if mp.MimeType == "" {
+					mp.MimeType = "application/octet-stream"
+				}
+			case strings.HasPrefix(value, "<"):
+			default:
+				mp.Content = value
+			}
+		}
+
+		h := textproto.MIMEHeader{}
+		disp := fmt.Sprintf("form-data; name=%q", mp.ControlName)
+		if mp.FileName != "" {
+			disp += fmt.Sprintf("; filename=%q", mp.FileName)
+		}
+		h.Set("Content-Disposition", disp)
+		if mp.MimeType != "" {
+			h.Set("Content-Type", mp.MimeType)
+		}
+		w, err := mw.CreatePart(h)
+		if err != nil {
+			return err
+		}
+		w.Write([]byte(mp.Content))
+		pr.Multiparts = append(pr.Multiparts, mp)
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+	pr.Body = buf.Bytes()
+	pr.Headers.Set("Content-Type", mw.FormDataContentType())
+	return nil
+}
+
+// splitShellWords splits a command line the way bash would: single quotes,
+// double quotes with backslash escapes, $'...' ANSI-C strings, backslash
+// escapes and backslash-newline continuations.
+func splitShellWords(s string) ([]string, error) {
+	var (
+		words   []string
+		cur     strings.Builder
+		inWord  bool
+		rs      = []rune(s)
+		closeIn = func(q string) error { return fmt.Errorf("unterminated %s quote", q) }
+	)
+	for i := 0; i < len(rs); i++ {
+		c := rs[i]
+		switch {
+		case c == '\\' && i+1 < len(rs):
+			i++
+			if rs[i] == '\r' && i+1 < len(rs) && rs[i+1] == '\n' {
+				i++
+			}
+			if rs[i] == '\n' {
+				continue // line continuation
+			}
+			cur.WriteRune(rs[i])
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		case c == '\'':
+			j := i + 1
+			for j < len(rs) && rs[j] != '\'' {
+				j++
+			}
+			if j >= len(rs) {
+				return nil, closeIn("single")
+			}
+			cur.WriteString(string(rs[i+1 : j]))
+			i, inWord = j, true
+		case c == '$' && i+1 < len(rs) && rs[i+1] == '\'':
+			i += 2
+			for ; i < len(rs) && rs[i] != '\''; i++ {
+				if rs[i] != '\\' || i+1 >= len(rs) {
+					cur.WriteRune(rs[i])
+					continue
+				}
+				i++
+				switch rs[i] {
+				case 'n':
+					cur.WriteRune('\n')
+				case 'r':
+					cur.WriteRune('\r')
+				case 't':
+					cur.WriteRune('\t')
+				case 'u', 'x':
+					n := map[rune]int{'u': 4, 'x': 2}[rs[i]]
+					var v rune
+					j := i + 1
+					for ; j < len(rs) && j <= i+n && strings.ContainsRune("0123456789abcdefABCDEF", rs[j]); j++ {
+						v = v*16 + hexValue(rs[j])
+					}
+					if j == i+1 {
+						cur.WriteRune('\\')
+						cur.WriteRune(rs[i])
+						continue
+					}
+					cur.WriteRune(v)
+					i = j - 1
+				default:
+					cur.WriteRune(rs[i])
+				}
+			}
+			if i >= len(rs) {
+				return nil, closeIn("$'")
+			}
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(rs) && rs[i] != '"'; i++ {
+				if rs[i] == '\\' && i+1 < len(rs) && strings.ContainsRune("\"\\$`\n", rs[i+1]) {
+					i++
+					if rs[i] == '\n' {
+						continue
+					}
+				}
+				cur.WriteRune(rs[i])
+			}
+			if i >= len(rs) {
+				return nil, closeIn("double")
+			}
+			inWord = true
+		default:
+			cur.WriteRune(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
+
+func hexValue(r rune) rune {
+	switch {
+	case r >= '0' && r <= '9':
+		return r - '0'
+	case r >= 'a' && r <= 'f':
+		return r - 'a' + 10
+	default:
+		return r - 'A' + 10
+	}
+}
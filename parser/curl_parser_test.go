@@ -0,0 +1,116 @@
+package parser
+
+import (
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIsCurl(t *testing.T) {
+	for in, want := range map[string]bool{
+		"curl http://x":             true,
+		"  $ curl -s http://x":      true,
+		"curl.exe http://x":         true,
+		"Request method: GET":       false,
+		"curly http://x":            false,
+		"\ncurl -X POST http://x\n": true,
+	} {
+		if got := IsCurl(in); got != want {
+			t.Errorf("IsCurl(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestParseCurl(t *testing.T) {
+	cmd := `curl 'https://api.example.com/pets?limit=5' \
+  -X PUT \
+  -H 'Content-Type: application/json' \
+  -H "X-Trace: \"quoted\"" \
+  -u admin:s3cret \
+  --data-raw $'{"name":"rex",\n"tag":"it\'s"}' \
+  --compressed -sSL`
+
+	pr, err := ParseCurl(cmd)
+	if err != nil {
+		t.Fatalf("ParseCurl returned error: %v", err)
+	}
+	if pr.Method != "PUT" || pr.URI != "https://api.example.com/pets?limit=5" {
+		t.Errorf("unexpected request %s %s", pr.Method, pr.URI)
+	}
+	if pr.Params.Get("limit") != "5" {
+		t.Errorf("expected limit param, got %v", pr.Params)
+	}
+	expectedHeaders := textproto.MIMEHeader{
+		"Content-Type":  {"application/json"},
+		"X-Trace":       {`"quoted"`},
+		"Authorization": {"Basic YWRtaW46czNjcmV0"},
+	}
+	if !reflect.DeepEqual(pr.Headers, expectedHeaders) {
+		t.Errorf("Headers mismatch.\nExpected: %#v\nGot:      %#v", expectedHeaders, pr.Headers)
+	}
+	if want := "{\"name\":\"rex\",\n\"tag\":\"it's\"}"; string(pr.Body) != want {
+		t.Errorf("Expected body %q, got %q", want, pr.Body)
+	}
+}
+
+func TestParseCurlDefaults(t *testing.T) {
+	tests := []struct {
+		cmd, method, uri, body string
+	}{
+		{`curl example.com/pets`, "GET", "http://example.com/pets", ""},
+		{`curl -d a=1 -d b=2 --url http://x/form`, "POST", "http://x/form", "a=1&b=2"},
+		{`curl -G -d q=dog --data-urlencode "tag=a b" http://x/search`, "GET", "http://x/search?q=dog&tag=a+b", ""},
+		{`curl -I http://x/health`, "HEAD", "http://x/health", ""},
+		{`curl -XDELETE http://x/pets/1`, "DELETE", "http://x/pets/1", ""},
+	}
+	for _, tt := range tests {
+		pr, err := ParseCurl(tt.cmd)
+		if err != nil {
+			t.Errorf("%s: %v", tt.cmd, err)
+			continue
+		}
+		if pr.Method != tt.method || pr.URI != tt.uri || string(pr.Body) != tt.body {
+			t.Errorf("%s: got %s %s %q", tt.cmd, pr.Method, pr.URI, pr.Body)
+		}
+	}
+
+	if _, err := ParseCurl(`curl -d @payload.json http://x`); err == nil {
+		t.Error("expected an error for a data file")
+	}
+	if _, err := ParseCurl(`curl -H 'X: unterminated http://x`); err == nil {
+		t.Error("expected an error for an unterminated quote")
+	}
+}
+
+func TestParseCurlForm(t *testing.T) {
+	pr, err := ParseCurl(`curl -F 'meta={"a":1};type=application/json' -F file=@/tmp/dog.png --form-string 'note=@literal' http://x/upload`)
+	if err != nil {
+		t.Fatalf("ParseCurl returned error: %v", err)
+	}
+	if pr.Method != "POST" {
+		t.Errorf("expected POST, got %s", pr.Method)
+	}
+	want := []Multipart{
+		{Disposition: "form-data", ControlName: "meta", MimeType: "application/json", Content: `{"a":1}`},
+		{Disposition: "form-data", ControlName: "file", FileName: "dog.png", MimeType: "application/octet-stream"},
+		{Disposition: "form-data", ControlName: "note", Content: "@literal"},
+	}
+	if !reflect.DeepEqual(pr.Multiparts, want) {
+		t.Errorf("Multiparts mismatch.\nExpected: %#v\nGot:      %#v", want, pr.Multiparts)
+	}
+
+	_, params, err := mime.ParseMediaType(pr.Headers.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	form, err := multipart.NewReader(strings.NewReader(string(pr.Body)), params["boundary"]).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatalf("body is not multipart: %v", err)
+	}
+	if form.Value["meta"][0] != `{"a":1}` || form.Value["note"][0] != "@literal" || form.File["file"][0].Filename != "dog.png" {
+		t.Errorf("unexpected form %#v", form)
+	}
+}
@@ -0,0 +1,443 @@
+package parser
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Exporters turn a ParsedRequest back into something a developer can run or
+// import. They expect PathParams to hold the params FindOperation matched,
+// which lets the RestAssured and Postman exports write the path as a
+// template.
+
+// ToCurl renders pr as a bash curl command.
+func ToCurl(pr ParsedRequest) string {
+	head := "curl"
+	if pr.Method != "GET" || len(pr.Body) > 0 {
+		head += " -X " + pr.Method
+	}
+	args := []string{head + " " + shellQuote(pr.URI)}
+	isForm := len(pr.Multiparts) > 0
+	for _, k := range sortedKeys(pr.Headers) {
+		if isForm && k == "Content-Type" {
+			continue // curl writes its own boundary
+		}
+		for _, v := range pr.Headers[k] {
+			args = append(args, "-H "+shellQuote(k+": "+v))
+		}
+	}
+	switch {
+	case isForm:
+		for _, mp := range pr.Multiparts {
+			args = append(args, "-F "+shellQuote(curlFormSpec(mp)))
+		}
+	case len(pr.Body) > 0:
+		args = append(args, "--data-raw "+shellQuote(string(pr.Body)))
+	}
+	return strings.Join(args, " \\\n  ") + "\n"
+}
+
+func curlFormSpec(mp Multipart) string {
+	spec := mp.ControlName + "="
+	if mp.FileName != "" {
+		spec += "@" + mp.FileName
+	} else {
+		spec += mp.Content
+	}
+	if mp.MimeType != "" {
+		spec += ";type=" + mp.MimeType
+	}
+	return spec
+}
+
+// ToHTTPie renders pr as an HTTPie command.
+func ToHTTPie(pr ParsedRequest) string {
+	head := "http"
+	switch {
+	case len(pr.Multiparts) > 0:
+		head += " --multipart"
+	case len(pr.FormParams) > 0:
+		head += " --form"
+	}
+	args := []string{head + " " + pr.Method + " " + shellQuote(pr.URI)}
+	for _, k := range sortedKeys(pr.Headers) {
+		if len(pr.Multiparts) > 0 && k == "Content-Type" {
+			continue
+		}
+		for _, v := range pr.Headers[k] {
+			args = append(args, shellQuote(k+":"+v))
+		}
+	}
+	switch {
+	case len(pr.Multiparts) > 0:
+		for _, mp := range pr.Multiparts {
+			if mp.FileName != "" {
+				item := mp.ControlName + "@" + mp.FileName
+				if mp.MimeType != "" {
+					item += ";type=" + mp.MimeType
+				}
+				args = append(args, shellQuote(item))
+			} else {
+				args = append(args, shellQuote(mp.ControlName+"="+mp.Content))
+			}
+		}
+	case len(pr.FormParams) > 0:
+		for _, p := range pr.FormParams {
+			args = append(args, shellQuote(p.Name+"="+p.Value))
+		}
+	case len(pr.Body) > 0:
+		args = append(args, "--raw "+shellQuote(string(pr.Body)))
+	}
+	return strings.Join(args, " \\\n  ") + "\n"
+}
+
+// ToHAR renders pr, and pres when known, as a HAR 1.2 log with one entry,
+// which browsers and HTTP clients can import.
+func ToHAR(pr ParsedRequest, pres *ParsedResponse) ([]byte, error) {
+	var h harFile
+	h.Log.Version = "1.2"
+	h.Log.Creator.Name = "better-docs"
+	h.Log.Creator.Version = "1.0"
+
+	e := harEntry{StartedDateTime: time.Now().UTC().Format(time.RFC3339Nano)}
+	e.Request = harRequest{
+		Method:      pr.Method,
+		URL:         pr.URI,
+		HTTPVersion: "HTTP/1.1",
+		Headers:     toHARHeaders(pr.Headers),
+		QueryString: []harNameValue{},
+		Cookies:     []harNameValue{},
+		HeadersSize: -1,
+		BodySize:    len(pr.Body),
+	}
+	for _, p := range orderedQuery(pr.URI) {
+		e.Request.QueryString = append(e.Request.QueryString, harNameValue{Name: p.Name, Value: p.Value})
+	}
+	for _, c := range pr.Cookies {
+		e.Request.Cookies = append(e.Request.Cookies, harNameValue{Name: c.Name, Value: c.Value})
+	}
+	if len(pr.Body) > 0 || len(pr.Multiparts) > 0 || len(pr.FormParams) > 0 {
+		pd := &harPostData{MimeType: pr.Headers.Get("Content-Type"), Text: string(pr.Body)}
+		for _, p := range pr.FormParams {
+			pd.Params = append(pd.Params, harNameValue{Name: p.Name, Value: p.Value})
+		}
+		for _, mp := range pr.Multiparts {
+			pd.Params = append(pd.Params, harNameValue{
+				Name: mp.ControlName, Value: mp.Content, FileName: mp.FileName, ContentType: mp.MimeType,
+			})
+		}
+		e.Request.PostData = pd
+	}
+
+	e.Response = harResponse{Headers: []harNameValue{}, Cookies: []harNameValue{}, HeadersSize: -1, BodySize: -1}
+	if pres != nil {
+		code := pres.StatusCode()
+		_, text, _ := strings.Cut(pres.Status, " ")
+		e.Response.Status, e.Response.StatusText = code, text
+		e.Response.HTTPVersion = pres.Proto
+		e.Response.Headers = toHARHeaders(pres.Headers)
+		e.Response.Content.MimeType = pres.Headers.Get("Content-Type")
+		e.Response.Content.Size = len(pres.Body)
+		e.Response.BodySize = len(pres.Body)
+		if utf8.Valid(pres.Body) {
+			e.Response.Content.Text = string(pres.Body)
+		} else {
+			e.Response.Content.Text = base64.StdEncoding.EncodeToString(pres.Body)
+			e.Response.Content.Encoding = "base64"
+		}
+		e.Response.RedirectURL = pres.Headers.Get("Location")
+	}
+
+	h.Log.Entries = []harEntry{e}
+	return json.MarshalIndent(h, "", "  ")
+}
+
+func toHARHeaders(h map[string][]string) []harNameValue {
+	out := []harNameValue{}
+	for _, k := range sortedKeys(h) {
+		for _, v := range h[k] {
+			out = append(out, harNameValue{Name: k, Value: v})
+		}
+	}
+	return out
+}
+
+// postmanItem is an item of a Postman v2.1 collection.
+type postmanItem struct {
+	Name    string `json:"name"`
+	Request struct {
+		Method string       `json:"method"`
+		Header []postmanKV  `json:"header"`
+		URL    postmanURL   `json:"url"`
+		Body   *postmanBody `json:"body,omitempty"`
+	} `json:"request"`
+}
+
+type postmanKV struct {
+	Key         string `json:"key"`
+	Value       string `json:"value,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Src         string `json:"src,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+}
+
+type postmanURL struct {
+	Raw      string      `json:"raw"`
+	Protocol string      `json:"protocol,omitempty"`
+	Host     []string    `json:"host"`
+	Port     string      `json:"port,omitempty"`
+	Path     []string    `json:"path"`
+	Query    []postmanKV `json:"query,omitempty"`
+	Variable []postmanKV `json:"variable,omitempty"`
+}
+
+type postmanBody struct {
+	Mode       string      `json:"mode"`
+	Raw        string      `json:"raw,omitempty"`
+	URLEncoded []postmanKV `json:"urlencoded,omitempty"`
+	FormData   []postmanKV `json:"formdata,omitempty"`
+	Options    interface{} `json:"options,omitempty"`
+}
+
+// ToPostmanItem renders pr as a Postman v2.1 collection item called name.
+// Matched path params become :name path variables.
+func ToPostmanItem(pr ParsedRequest, name string) ([]byte, error) {
+	u, err := url.Parse(pr.URI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URI: %w", err)
+	}
+	var item postmanItem
+	if name == "" {
+		name = pr.Method + " " + u.Path
+	}
+	item.Name = name
+	item.Request.Method = pr.Method
+	item.Request.Header = []postmanKV{}
+	for _, k := range sortedKeys(pr.Headers) {
+		if len(pr.Multiparts) > 0 && k == "Content-Type" {
+			continue
+		}
+		for _, v := range pr.Headers[k] {
+			item.Request.Header = append(item.Request.Header, postmanKV{Key: k, Value: v})
+		}
+	}
+
+	tmpl, used := templatePath(u.Path, pr.PathParams)
+	pu := postmanURL{Protocol: u.Scheme, Port: u.Port(), Host: strings.Split(u.Hostname(), "."), Path: []string{}}
+	for _, seg := range strings.Split(strings.Trim(tmpl, "/"), "/") {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			seg = ":" + seg[1:len(seg)-1]
+		}
+		if seg != "" {
+			pu.Path = append(pu.Path, seg)
+		}
+	}
+	for _, p := range orderedQuery(pr.URI) {
+		pu.Query = append(pu.Query, postmanKV{Key: p.Name, Value: p.Value})
+	}
+	for _, k := range used {
+		pu.Variable = append(pu.Variable, postmanKV{Key: k, Value: pr.PathParams[k]})
+	}
+	raw := *u
+	raw.Path, raw.RawPath = "/"+strings.Join(pu.Path, "/"), ""
+	pu.Raw = raw.String()
+	item.Request.URL = pu
+
+	switch {
+	case len(pr.Multiparts) > 0:
+		b := &postmanBody{Mode: "formdata"}
+		for _, mp := range pr.Multiparts {
+			kv := postmanKV{Key: mp.ControlName, Type: "text", Value: mp.Content, ContentType: mp.MimeType}
+			if mp.FileName != "" {
+				kv = postmanKV{Key: mp.ControlName, Type: "file", Src: mp.FileName, ContentType: mp.MimeType}
+			}
+			b.FormData = append(b.FormData, kv)
+		}
+		item.Request.Body = b
+	case len(pr.FormParams) > 0:
+		b := &postmanBody{Mode: "urlencoded"}
+		for _, p := range pr.FormParams {
+			b.URLEncoded = append(b.URLEncoded, postmanKV{Key: p.Name, Value: p.Value})
+		}
+		item.Request.Body = b
+	case len(pr.Body) > 0:
+		b := &postmanBody{Mode: "raw", Raw: string(pr.Body)}
+		if strings.Contains(pr.Headers.Get("Content-Type"), "json") {
+			b.Options = map[string]interface{}{"raw": map[string]string{"language": "json"}}
+		}
+		item.Request.Body = b
+	}
+	return json.MarshalIndent(item, "", "  ")
+}
+
+// ToRestAssured renders pr as a RestAssured given().when().then() snippet.
+// When pres is known the snippet asserts its status code.
+func ToRestAssured(pr ParsedRequest, pres *ParsedResponse) (string, error) {
+	u, err := url.Parse(pr.URI)
+	if err != nil {
+		return "", fmt.Errorf("invalid URI: %w", err)
+	}
+	var b strings.Builder
+	line := func(format string, args ...interface{}) {
+		fmt.Fprintf(&b, "    "+format+"\n", args...)
+	}
+
+	b.WriteString("given()\n")
+	if u.Host != "" {
+		line(".baseUri(%s)", javaString(u.Scheme+"://"+u.Host))
+	}
+	for _, k := range sortedKeys(pr.Headers) {
+		switch {
+		case k == "Content-Type" && len(pr.Multiparts) > 0:
+		case k == "Content-Type":
+			line(".contentType(%s)", javaString(pr.Headers.Get(k)))
+		case k == "Cookie" && len(pr.Cookies) > 0:
+		default:
+			for _, v := range pr.Headers[k] {
+				line(".header(%s, %s)", javaString(k), javaString(v))
+			}
+		}
+	}
+	for _, c := range pr.Cookies {
+		line(".cookie(%s, %s)", javaString(c.Name), javaString(c.Value))
+	}
+	for _, p := range orderedQuery(pr.URI) {
+		line(".queryParam(%s, %s)", javaString(p.Name), javaString(p.Value))
+	}
+	tmpl, used := templatePath(u.Path, pr.PathParams)
+	for _, k := range used {
+		line(".pathParam(%s, %s)", javaString(k), javaString(pr.PathParams[k]))
+	}
+	switch {
+	case len(pr.Multiparts) > 0:
+		for _, mp := range pr.Multiparts {
+			content := javaString(mp.Content)
+			if mp.FileName != "" {
+				content = "new File(" + javaString(mp.FileName) + ")"
+			}
+			if mp.MimeType != "" {
+				line(".multiPart(%s, %s, %s)", javaString(mp.ControlName), content, javaString(mp.MimeType))
+			} else {
+				line(".multiPart(%s, %s)", javaString(mp.ControlName), content)
+			}
+		}
+	case len(pr.FormParams) > 0:
+		for _, p := range pr.FormParams {
+			line(".formParam(%s, %s)", javaString(p.Name), javaString(p.Value))
+		}
+	case len(pr.Body) > 0:
+		line(".body(%s)", javaString(string(pr.Body)))
+	}
+
+	b.WriteString(".when()\n")
+	line(".%s(%s)", strings.ToLower(pr.Method), javaString(tmpl))
+	b.WriteString(".then()\n")
+	if pres != nil && pres.StatusCode() != 0 {
+		line(".statusCode(%d);", pres.StatusCode())
+	} else {
+		line(".log().all();")
+	}
+	return b.String(), nil
+}
+
+// templatePath puts {name} back for each path segment that holds the value
+// of a matched path param, returning the names used in path order.
+func templatePath(path string, params map[string]string) (string, []string) {
+	if len(params) == 0 {
+		return path, nil
+	}
+	names := make([]string, 0, len(params))
+	for k := range params {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	taken := map[string]bool{}
+	var used []string
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		if unescaped, err := url.PathUnescape(seg); err == nil {
+			seg = unescaped
+		}
+		for _, k := range names {
+			if !taken[k] && seg != "" && params[k] == seg {
+				segs[i] = "{" + k + "}"
+				taken[k] = true
+				used = append(used, k)
+				break
+			}
+		}
+	}
+	return strings.Join(segs, "/"), used
+}
+
+// orderedQuery returns the query params of rawURL in the order written.
+func orderedQuery(rawURL string) []Param {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return nil
+	}
+	var out []Param
+	for _, kv := range strings.Split(u.RawQuery, "&") {
+		if kv == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(kv, "=")
+		if uk, err := url.QueryUnescape(k); err == nil {
+			k = uk
+		}
+		if uv, err := url.QueryUnescape(v); err == nil {
+			v = uv
+		}
+		out = append(out, Param{Name: k, Value: v})
+	}
+	return out
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// shellQuote single-quotes s for bash.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// javaString quotes s as a Java string literal.
+func javaString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				b.WriteString(`\u` + fmt.Sprintf("%04x", r))
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
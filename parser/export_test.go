@@ -0,0 +1,123 @@
+package parser
+
+import (
+	"encoding/json"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func exportRequest() ParsedRequest {
+	return ParsedRequest{
+		Method: "PUT",
+		URI:    "https://api.example.com/v2/pets/7?dryRun=true",
+		Headers: textproto.MIMEHeader{
+			"Content-Type": {"application/json"},
+			"X-Note":       {"it's"},
+		},
+		Params:     url.Values{"dryRun": {"true"}},
+		Body:       []byte(`{"name":"rex"}`),
+		PathParams: map[string]string{"petId": "7"},
+	}
+}
+
+func TestToCurlRoundTrip(t *testing.T) {
+	cmd := ToCurl(exportRequest())
+	pr, err := ParseCurl(cmd)
+	if err != nil {
+		t.Fatalf("exported command does not parse: %v\n%s", err, cmd)
+	}
+	want := exportRequest()
+	if pr.Method != want.Method || pr.URI != want.URI || string(pr.Body) != string(want.Body) {
+		t.Errorf("round trip changed the request: %s %s %q", pr.Method, pr.URI, pr.Body)
+	}
+	if pr.Headers.Get("X-Note") != "it's" {
+		t.Errorf("quoted header lost: %#v", pr.Headers)
+	}
+}
+
+func TestToHTTPie(t *testing.T) {
+	want := "http PUT 'https://api.example.com/v2/pets/7?dryRun=true' \\\n" +
+		"  'Content-Type:application/json' \\\n" +
+		"  'X-Note:it'\\''s' \\\n" +
+		"  --raw '{\"name\":\"rex\"}'\n"
+	if got := ToHTTPie(exportRequest()); got != want {
+		t.Errorf("Expected:\n%s\nGot:\n%s", want, got)
+	}
+}
+
+func TestToHARRoundTrip(t *testing.T) {
+	pres := &ParsedResponse{Proto: "HTTP/1.1", Status: "200 OK", Headers: textproto.MIMEHeader{"Content-Type": {"application/json"}}, Body: []byte(`{"id":7}`)}
+	data, err := ToHAR(exportRequest(), pres)
+	if err != nil {
+		t.Fatalf("ToHAR returned error: %v", err)
+	}
+	exs, err := ReadHAR(strings.NewReader(string(data)))
+	if err != nil || len(exs) != 1 {
+		t.Fatalf("exported HAR does not read back: %v", err)
+	}
+	got := exs[0]
+	if got.Request.Method != "PUT" || got.Request.URI != exportRequest().URI || string(got.Request.Body) != `{"name":"rex"}` {
+		t.Errorf("unexpected request %#v", got.Request)
+	}
+	if got.Response == nil || got.Response.Status != "200 OK" || string(got.Response.Body) != `{"id":7}` {
+		t.Errorf("unexpected response %#v", got.Response)
+	}
+}
+
+func TestToPostmanItem(t *testing.T) {
+	data, err := ToPostmanItem(exportRequest(), "updatePet")
+	if err != nil {
+		t.Fatalf("ToPostmanItem returned error: %v", err)
+	}
+	var item struct {
+		Name    string
+		Request struct {
+			Method string
+			URL    struct {
+				Raw      string
+				Path     []string
+				Variable []struct{ Key, Value string }
+			}
+			Body struct{ Mode, Raw string }
+		}
+	}
+	if err := json.Unmarshal(data, &item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Name != "updatePet" || item.Request.Method != "PUT" {
+		t.Errorf("unexpected item %s %s", item.Name, item.Request.Method)
+	}
+	if item.Request.URL.Raw != "https://api.example.com/v2/pets/:petId?dryRun=true" {
+		t.Errorf("unexpected raw URL %q", item.Request.URL.Raw)
+	}
+	if v := item.Request.URL.Variable; len(v) != 1 || v[0].Key != "petId" || v[0].Value != "7" {
+		t.Errorf("unexpected variables %#v", v)
+	}
+	if item.Request.Body.Mode != "raw" || item.Request.Body.Raw != `{"name":"rex"}` {
+		t.Errorf("unexpected body %#v", item.Request.Body)
+	}
+}
+
+func TestToRestAssured(t *testing.T) {
+	got, err := ToRestAssured(exportRequest(), &ParsedResponse{Status: "200 OK"})
+	if err != nil {
+		t.Fatalf("ToRestAssured returned error: %v", err)
+	}
+	want := `given()
+    .baseUri("https://api.example.com")
+    .contentType("application/json")
+    .header("X-Note", "it's")
+    .queryParam("dryRun", "true")
+    .pathParam("petId", "7")
+    .body("{\"name\":\"rex\"}")
+.when()
+    .put("/v2/pets/{petId}")
+.then()
+    .statusCode(200);
+`
+	if got != want {
+		t.Errorf("Expected:\n%s\nGot:\n%s", want, got)
+	}
+}
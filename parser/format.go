@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+)
+
+// Format parses one kind of request log, e.g. RestAssured console output or
+// a curl command.
+type Format interface {
+	// Name identifies the format in API responses.
+	Name() string
+	// Detect reports whether input looks like this format.
+	Detect(input string) bool
+	// Parse returns the first request in input and, when the input holds
+	// one, its response.
+	Parse(input string) (ParsedRequest, *ParsedResponse, error)
+}
+
+// Formats are the formats Detect tries, in order. RestAssured is not listed:
+// it is the fallback for input no other format claims.
+var Formats = []Format{
+	CurlFormat{},
+	MockMvcFormat{},
+	OkHttpFormat{},
+	RawHTTPFormat{},
+}
+
+// Detect returns the format of input, falling back to RestAssuredFormat.
+func Detect(input string) Format {
+	for _, f := range Formats {
+		if f.Detect(input) {
+			return f
+		}
+	}
+	return RestAssuredFormat{}
+}
+
+// Parse detects the format of input and parses it.
+func Parse(input string) (ParsedRequest, *ParsedResponse, Format, error) {
+	f := Detect(input)
+	pr, pres, err := f.Parse(input)
+	return pr, pres, f, err
+}
+
+// RestAssuredFormat is RestAssured's request and response logging, as read
+// by ParseLog. Prefix replaces DefaultLogPrefix when set.
+type RestAssuredFormat struct {
+	Prefix *regexp.Regexp
+}
+
+func (RestAssuredFormat) Name() string { return "restassured" }
+
+func (RestAssuredFormat) Detect(input string) bool {
+	return strings.Contains(input, "Request method:")
+}
+
+func (f RestAssuredFormat) Parse(input string) (ParsedRequest, *ParsedResponse, error) {
+	sc := NewLogScanner(bufio.NewReader(strings.NewReader(input)))
+	if f.Prefix != nil {
+		sc.SetPrefix(f.Prefix)
+	}
+	return sc.First()
+}
+
+// CurlFormat is a curl command line, as read by ParseCurl.
+type CurlFormat struct{}
+
+func (CurlFormat) Name() string { return "curl" }
+
+func (CurlFormat) Detect(input string) bool { return IsCurl(input) }
+
+func (CurlFormat) Parse(input string) (ParsedRequest, *ParsedResponse, error) {
+	pr, err := ParseCurl(input)
+	return pr, nil, err
+}
@@ -0,0 +1,122 @@
+package parser
+
+import (
+	"net/textproto"
+	"reflect"
+	"testing"
+)
+
+const mockMockMvcLog = `
+MockHttpServletRequest:
+      HTTP Method = POST
+      Request URI = /api/pets
+       Parameters = {dryRun=[true], tag=[a, b]}
+          Headers = [Content-Type:"application/json;charset=UTF-8", Accept:"application/json", "text/plain"]
+             Body = {"name":"rex"}
+    Session Attrs = {}
+
+Handler:
+             Type = com.example.PetController
+           Method = com.example.PetController#create(Pet)
+
+MockHttpServletResponse:
+           Status = 201
+    Error message = null
+          Headers = [Location:"http://localhost/api/pets/9", Content-Type:"application/json"]
+     Content type = application/json
+             Body = {"id":9}
+    Forwarded URL = null
+          Cookies = []
+`
+
+const mockOkHttpLog = `2024-05-01 12:00:00.100 1234-5678/com.app D/OkHttp: --> PUT https://api.example.com/pets/9 http/1.1
+2024-05-01 12:00:00.100 1234-5678/com.app D/OkHttp: Content-Type: application/json; charset=utf-8
+2024-05-01 12:00:00.100 1234-5678/com.app D/OkHttp: Authorization: Bearer abc
+2024-05-01 12:00:00.100 1234-5678/com.app D/OkHttp: 
+2024-05-01 12:00:00.100 1234-5678/com.app D/OkHttp: {"name":"rex"}
+2024-05-01 12:00:00.100 1234-5678/com.app D/OkHttp: --> END PUT (14-byte body)
+2024-05-01 12:00:00.200 1234-5678/com.app D/OkHttp: <-- 409 Conflict https://api.example.com/pets/9 (98ms)
+2024-05-01 12:00:00.200 1234-5678/com.app D/OkHttp: content-type: application/json
+2024-05-01 12:00:00.200 1234-5678/com.app D/OkHttp: 
+2024-05-01 12:00:00.200 1234-5678/com.app D/OkHttp: {"error":"exists"}
+2024-05-01 12:00:00.200 1234-5678/com.app D/OkHttp: <-- END HTTP (18-byte body)
+`
+
+const mockRawHTTP = "GET /pets?limit=2 HTTP/1.1\r\n" +
+	"Host: api.example.com\r\n" +
+	"Accept: application/json\r\n" +
+	"\r\n" +
+	"HTTP/1.1 200 OK\r\n" +
+	"Content-Type: application/json\r\n" +
+	"\r\n" +
+	"[]\r\n"
+
+func TestDetect(t *testing.T) {
+	for in, want := range map[string]string{
+		mockRestAssuredLog:     "restassured",
+		mockLogbackLog:         "restassured",
+		"curl -X GET http://x": "curl",
+		mockMockMvcLog:         "mockmvc",
+		mockOkHttpLog:          "okhttp",
+		mockRawHTTP:            "http",
+	} {
+		if got := Detect(in).Name(); got != want {
+			t.Errorf("Detect(%.30q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestMockMvcFormat(t *testing.T) {
+	pr, pres, err := MockMvcFormat{}.Parse(mockMockMvcLog)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if pr.Method != "POST" || pr.URI != "/api/pets?dryRun=true&tag=a&tag=b" {
+		t.Errorf("unexpected request %s %s", pr.Method, pr.URI)
+	}
+	expectedHeaders := textproto.MIMEHeader{
+		"Content-Type": {"application/json;charset=UTF-8"},
+		"Accept":       {"application/json", "text/plain"},
+	}
+	if !reflect.DeepEqual(pr.Headers, expectedHeaders) {
+		t.Errorf("Headers mismatch.\nExpected: %#v\nGot:      %#v", expectedHeaders, pr.Headers)
+	}
+	if string(pr.Body) != `{"name":"rex"}` {
+		t.Errorf("unexpected body %q", pr.Body)
+	}
+	if pres == nil || pres.Status != "201 Created" || pres.Headers.Get("Location") != "http://localhost/api/pets/9" || string(pres.Body) != `{"id":9}` {
+		t.Errorf("unexpected response %#v", pres)
+	}
+}
+
+func TestOkHttpFormat(t *testing.T) {
+	pr, pres, err := OkHttpFormat{}.Parse(mockOkHttpLog)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if pr.Method != "PUT" || pr.URI != "https://api.example.com/pets/9" {
+		t.Errorf("unexpected request %s %s", pr.Method, pr.URI)
+	}
+	if pr.Headers.Get("Authorization") != "Bearer abc" || string(pr.Body) != `{"name":"rex"}` {
+		t.Errorf("unexpected request headers or body %#v %q", pr.Headers, pr.Body)
+	}
+	if pres == nil || pres.Status != "409 Conflict" || pres.Headers.Get("Content-Type") != "application/json" || string(pres.Body) != `{"error":"exists"}` {
+		t.Errorf("unexpected response %#v", pres)
+	}
+}
+
+func TestRawHTTPFormat(t *testing.T) {
+	pr, pres, err := RawHTTPFormat{}.Parse(mockRawHTTP)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if pr.Method != "GET" || pr.URI != "http://api.example.com/pets?limit=2" || pr.Params.Get("limit") != "2" {
+		t.Errorf("unexpected request %s %s", pr.Method, pr.URI)
+	}
+	if len(pr.Body) != 0 {
+		t.Errorf("expected no body, got %q", pr.Body)
+	}
+	if pres == nil || pres.Proto != "HTTP/1.1" || pres.Status != "200 OK" || string(pres.Body) != "[]" {
+		t.Errorf("unexpected response %#v", pres)
+	}
+}
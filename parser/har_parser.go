@@ -0,0 +1,234 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+)
+
+// harFile is the subset of HAR 1.2 (http://www.softwareishard.com/blog/har-12-spec/)
+// needed to rebuild requests and responses, as exported by browser DevTools,
+// proxies such as Charles or mitmproxy, and this server's /recordings. It
+// carries the fields the spec requires so ToHAR can write it too.
+type harFile struct {
+	Log struct {
+		Version string `json:"version"`
+		Creator struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         struct {
+		Send    float64 `json:"send"`
+		Wait    float64 `json:"wait"`
+		Receive float64 `json:"receive"`
+	} `json:"timings"`
+}
+
+type harNameValue struct {
+	Name        string `json:"name"`
+	Value       string `json:"value"`
+	FileName    string `json:"fileName,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+}
+
+type harPostData struct {
+	MimeType string         `json:"mimeType"`
+	Text     string         `json:"text"`
+	Params   []harNameValue `json:"params,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	Cookies     []harNameValue `json:"cookies"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []harNameValue `json:"headers"`
+	Cookies     []harNameValue `json:"cookies"`
+	Content     struct {
+		Size     int    `json:"size"`
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text,omitempty"`
+		Encoding string `json:"encoding,omitempty"`
+	} `json:"content"`
+	RedirectURL string `json:"redirectURL"`
+	HeadersSize int    `json:"headersSize"`
+	BodySize    int    `json:"bodySize"`
+}
+
+// ReadHAR decodes a HAR document into one Exchange per entry, in log order.
+// Entries that never got a response (status 0, e.g. blocked or aborted in
+// the browser) have a nil Response. HAR entries carry no line offsets.
+func ReadHAR(r io.Reader) ([]Exchange, error) {
+	var har harFile
+	if err := json.NewDecoder(r).Decode(&har); err != nil {
+		return nil, fmt.Errorf("invalid HAR: %w", err)
+	}
+	out := make([]Exchange, 0, len(har.Log.Entries))
+	for _, e := range har.Log.Entries {
+		ex := Exchange{Request: harToRequest(e.Request)}
+		if ex.Request.Method == "" || ex.Request.URI == "" {
+			ex.Err = fmt.Errorf("missing method or URI")
+		}
+		if e.Response.Status != 0 {
+			pres, err := harToResponse(e.Response)
+			if err != nil && ex.Err == nil {
+				ex.Err = err
+			}
+			ex.Response = &pres
+		}
+		out = append(out, ex)
+	}
+	return out, nil
+}
+
+func harToRequest(hr harRequest) ParsedRequest {
+	pr := ParsedRequest{
+		Method:  strings.ToUpper(hr.Method),
+		URI:     hr.URL,
+		Headers: harHeaders(hr.Headers),
+		Params:  url.Values{},
+	}
+	if u, err := url.Parse(hr.URL); err == nil {
+		pr.Params = u.Query()
+	}
+	for _, c := range hr.Cookies {
+		pr.Cookies = append(pr.Cookies, Param{Name: c.Name, Value: c.Value})
+	}
+
+	pd := hr.PostData
+	if pd == nil {
+		return pr
+	}
+	if pr.Headers.Get("Content-Type") == "" && pd.MimeType != "" {
+		pr.Headers.Set("Content-Type", pd.MimeType)
+	}
+	pr.Body = []byte(pd.Text)
+
+	mt := strings.ToLower(pd.MimeType)
+	switch {
+	case strings.HasPrefix(mt, "multipart/"):
+		for _, p := range pd.Params {
+			pr.Multiparts = append(pr.Multiparts, Multipart{
+				Disposition: "form-data",
+				ControlName: p.Name,
+				FileName:    p.FileName,
+				MimeType:    p.ContentType,
+				Content:     p.Value,
+			})
+		}
+		if pd.Text == "" && len(pr.Multiparts) > 0 {
+			pr.Body, pr.Headers = harMultipartBody(pr.Multiparts, pr.Headers)
+		}
+	case strings.HasPrefix(mt, "application/x-www-form-urlencoded"):
+		form := url.Values{}
+		for _, p := range pd.Params {
+			pr.FormParams = append(pr.FormParams, Param{Name: p.Name, Value: p.Value})
+			form.Add(p.Name, p.Value)
+		}
+		if pd.Text == "" {
+			pr.Body = []byte(form.Encode())
+		}
+	}
+	return pr
+}
+
+// harMultipartBody encodes parts for exports that list params but omit the
+// raw text. The boundary in the Content-Type is replaced to match.
+func harMultipartBody(parts []Multipart, h textproto.MIMEHeader) ([]byte, textproto.MIMEHeader) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, mp := range parts {
+		ph := textproto.MIMEHeader{}
+		disp := fmt.Sprintf("form-data; name=%q", mp.ControlName)
+		if mp.FileName != "" {
+			disp += fmt.Sprintf("; filename=%q", mp.FileName)
+		}
+		ph.Set("Content-Disposition", disp)
+		if mp.MimeType != "" {
+			ph.Set("Content-Type", mp.MimeType)
+		}
+		if w, err := mw.CreatePart(ph); err == nil {
+			w.Write([]byte(mp.Content))
+		}
+	}
+	mw.Close()
+	h.Set("Content-Type", mw.FormDataContentType())
+	return buf.Bytes(), h
+}
+
+func harToResponse(hr harResponse) (ParsedResponse, error) {
+	pres := ParsedResponse{
+		Proto:   harProto(hr.HTTPVersion),
+		Status:  fmt.Sprintf("%d %s", hr.Status, hr.StatusText),
+		Headers: harHeaders(hr.Headers),
+	}
+	if hr.StatusText == "" {
+		pres.Status = fmt.Sprintf("%d %s", hr.Status, http.StatusText(hr.Status))
+	}
+	pres.Status = strings.TrimSpace(pres.Status)
+
+	if hr.Content.Encoding == "base64" {
+		body, err := base64.StdEncoding.DecodeString(hr.Content.Text)
+		if err != nil {
+			return pres, fmt.Errorf("response content: %w", err)
+		}
+		pres.Body = body
+	} else {
+		pres.Body = []byte(hr.Content.Text)
+	}
+	return pres, nil
+}
+
+// harHeaders converts a HAR header list, dropping HTTP/2 pseudo-headers.
+func harHeaders(nvs []harNameValue) textproto.MIMEHeader {
+	h := textproto.MIMEHeader{}
+	for _, nv := range nvs {
+		if strings.HasPrefix(nv.Name, ":") {
+			continue
+		}
+		h.Add(textproto.CanonicalMIMEHeaderKey(nv.Name), nv.Value)
+	}
+	return h
+}
+
+// harProto normalises the httpVersion spellings browsers use ("h2",
+// "http/2.0") to the form RestAssured logs.
+func harProto(v string) string {
+	switch strings.ToLower(v) {
+	case "", "unknown":
+		return "HTTP/1.1"
+	case "h2", "http/2", "http/2.0":
+		return "HTTP/2"
+	case "h3", "http/3", "http/3.0":
+		return "HTTP/3"
+	}
+	return strings.ToUpper(v)
+}
@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const mockHAR = `{"log": {"version": "1.2", "entries": [
+  {"request": {"method": "post", "url": "https://api.example.com/pets?dry=1", "httpVersion": "h2",
+     "headers": [{"name": ":authority", "value": "api.example.com"}, {"name": "content-type", "value": "application/json"}],
+     "cookies": [{"name": "sid", "value": "42"}],
+     "postData": {"mimeType": "application/json", "text": "{\"name\":\"rex\"}"}},
+   "response": {"status": 201, "statusText": "", "httpVersion": "h2",
+     "headers": [{"name": "location", "value": "/pets/9"}],
+     "content": {"mimeType": "application/json", "text": "eyJpZCI6OX0=", "encoding": "base64"}}},
+  {"request": {"method": "POST", "url": "https://api.example.com/login", "headers": [],
+     "postData": {"mimeType": "application/x-www-form-urlencoded", "params": [{"name": "user", "value": "a b"}]}},
+   "response": {"status": 0, "headers": [], "content": {}}}
+]}}`
+
+func TestReadHAR(t *testing.T) {
+	exs, err := ReadHAR(strings.NewReader(mockHAR))
+	if err != nil {
+		t.Fatalf("ReadHAR returned error: %v", err)
+	}
+	if len(exs) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(exs))
+	}
+
+	pr, pres := exs[0].Request, exs[0].Response
+	if pr.Method != "POST" || pr.URI != "https://api.example.com/pets?dry=1" || pr.Params.Get("dry") != "1" {
+		t.Errorf("unexpected request %s %s %v", pr.Method, pr.URI, pr.Params)
+	}
+	if _, ok := pr.Headers[":authority"]; ok || pr.Headers.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected headers %#v", pr.Headers)
+	}
+	if want := []Param{{"sid", "42"}}; !reflect.DeepEqual(pr.Cookies, want) {
+		t.Errorf("Cookies mismatch: %#v", pr.Cookies)
+	}
+	if string(pr.Body) != `{"name":"rex"}` {
+		t.Errorf("unexpected body %q", pr.Body)
+	}
+	if pres == nil || pres.Proto != "HTTP/2" || pres.Status != "201 Created" || string(pres.Body) != `{"id":9}` {
+		t.Errorf("unexpected response %#v", pres)
+	}
+
+	pr = exs[1].Request
+	if string(pr.Body) != "user=a+b" || !reflect.DeepEqual(pr.FormParams, []Param{{"user", "a b"}}) {
+		t.Errorf("unexpected form request %q %#v", pr.Body, pr.FormParams)
+	}
+	if pr.Headers.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		t.Errorf("expected the post data mime type as Content-Type, got %q", pr.Headers.Get("Content-Type"))
+	}
+	if exs[1].Response != nil {
+		t.Errorf("expected no response for a status 0 entry, got %#v", exs[1].Response)
+	}
+
+	if _, err := ReadHAR(strings.NewReader("not json")); err == nil {
+		t.Error("expected an error for invalid input")
+	}
+}
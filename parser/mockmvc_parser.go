@@ -0,0 +1,167 @@
+package parser
+
+import (
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// mockMvcField matches the right-aligned "Name = value" lines of MockMvc's
+// print().
+var mockMvcField = regexp.MustCompile(`^\s*([A-Za-z][A-Za-z ]*?) =(?: (.*))?$`)
+
+// mockMvcSection matches a section title such as "MockHttpServletRequest:".
+var mockMvcSection = regexp.MustCompile(`^\s*([A-Za-z ]+):\s*$`)
+
+// MockMvcFormat is the output of Spring MockMvc's print() result handler.
+// MockMvc logs the path alone, so the request URI is relative; callers
+// resolve it against the spec's server.
+type MockMvcFormat struct{}
+
+func (MockMvcFormat) Name() string { return "mockmvc" }
+
+func (MockMvcFormat) Detect(input string) bool {
+	return strings.Contains(input, "MockHttpServletRequest:")
+}
+
+func (MockMvcFormat) Parse(input string) (ParsedRequest, *ParsedResponse, error) {
+	pr := ParsedRequest{Headers: textproto.MIMEHeader{}, Params: url.Values{}}
+	var pres *ParsedResponse
+
+	section := ""
+	fields := map[string]map[string]string{}
+	var last string // field the previous line belonged to, for multi-line bodies
+	for _, line := range strings.Split(input, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if m := mockMvcSection.FindStringSubmatch(line); m != nil {
+			section, last = m[1], ""
+			fields[section] = map[string]string{}
+			continue
+		}
+		if section == "" {
+			continue
+		}
+		if m := mockMvcField.FindStringSubmatch(line); m != nil {
+			last = m[1]
+			fields[section][last] = m[2]
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			last = ""
+			continue
+		}
+		if last == "Body" {
+			fields[section][last] += "\n" + line
+		}
+	}
+
+	req := fields["MockHttpServletRequest"]
+	if req == nil {
+		return pr, nil, fmt.Errorf("missing MockHttpServletRequest section")
+	}
+	pr.Method = strings.ToUpper(req["HTTP Method"])
+	path := req["Request URI"]
+	pr.Headers = parseSpringHeaders(req["Headers"])
+	if b := req["Body"]; b != "" && b != "null" && b != "<no character encoding set>" {
+		pr.Body = []byte(b)
+	}
+
+	params := parseSpringMultiMap(req["Parameters"])
+	isForm := strings.HasPrefix(pr.Headers.Get("Content-Type"), "application/x-www-form-urlencoded")
+	q := url.Values{}
+	for _, p := range params {
+		if isForm {
+			pr.FormParams = append(pr.FormParams, p)
+		} else {
+			q.Add(p.Name, p.Value)
+		}
+	}
+	if isForm && len(pr.Body) == 0 {
+		form := url.Values{}
+		for _, p := range pr.FormParams {
+			form.Add(p.Name, p.Value)
+		}
+		pr.Body = []byte(form.Encode())
+	}
+	if path != "" {
+		u := &url.URL{Path: path, RawQuery: q.Encode()}
+		pr.URI = u.String()
+		pr.Params = q
+	}
+
+	if resp := fields["MockHttpServletResponse"]; resp != nil {
+		code, err := strconv.Atoi(strings.TrimSpace(resp["Status"]))
+		if err == nil {
+			pres = &ParsedResponse{
+				Proto:   "HTTP/1.1",
+				Status:  strings.TrimSpace(fmt.Sprintf("%d %s", code, http.StatusText(code))),
+				Headers: parseSpringHeaders(resp["Headers"]),
+			}
+			if b := resp["Body"]; b != "" && b != "null" {
+				pres.Body = []byte(b)
+			}
+		}
+	}
+
+	if pr.Method == "" || pr.URI == "" {
+		return pr, pres, fmt.Errorf("missing method or URI")
+	}
+	return pr, pres, nil
+}
+
+// parseSpringHeaders reads HttpHeaders.toString():
+// [Content-Type:"application/json", Accept:"text/plain", "application/json"].
+func parseSpringHeaders(s string) textproto.MIMEHeader {
+	h := textproto.MIMEHeader{}
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	name := ""
+	for s != "" {
+		s = strings.TrimLeft(s, ", ")
+		if s == "" {
+			break
+		}
+		if s[0] != '"' {
+			i := strings.Index(s, ":")
+			if i < 0 {
+				break
+			}
+			name = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(s[:i]))
+			s = s[i+1:]
+			continue
+		}
+		end := strings.Index(s[1:], `"`)
+		if end < 0 {
+			h.Add(name, s[1:])
+			break
+		}
+		h.Add(name, s[1:end+1])
+		s = s[end+2:]
+	}
+	return h
+}
+
+// parseSpringMultiMap reads a Map<String, String[]> toString():
+// {name=[rex], tag=[a, b]}.
+func parseSpringMultiMap(s string) []Param {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}")
+	var out []Param
+	for s != "" {
+		eq := strings.Index(s, "=[")
+		end := strings.Index(s, "]")
+		if eq < 0 || end < eq {
+			break
+		}
+		name := strings.TrimSpace(strings.TrimLeft(s[:eq], ", "))
+		for _, v := range strings.Split(s[eq+2:end], ", ") {
+			out = append(out, Param{Name: name, Value: v})
+		}
+		s = s[end+1:]
+	}
+	return out
+}
@@ -0,0 +1,169 @@
+package parser
+
+import (
+	"fmt"
+	"net/textproto"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// logcatTag matches the Android logcat prefix OkHttp lines carry on device,
+// e.g. "2024-05-01 12:00:00.123 1234-5678/com.app D/OkHttp: ".
+var logcatTag = regexp.MustCompile(`^.*?\b[VDIWEF]/[\w.$-]+(?:\(\s*\d+\))?: `)
+
+// okHttpEnd matches the closing lines "--> END POST" and "<-- END HTTP",
+// with the body summary that may follow them.
+var okHttpEnd = regexp.MustCompile(`^(?:-->|<--) END\b`)
+
+// okHttpOmitted matches the notes OkHttp writes instead of a body.
+var okHttpOmitted = regexp.MustCompile(`^\((?:binary|encoded|duplex|one-shot|\d+-byte).*\)$|body omitted`)
+
+// OkHttpFormat is the output of OkHttp's HttpLoggingInterceptor at the
+// BASIC, HEADERS or BODY level, with or without logger or logcat prefixes.
+type OkHttpFormat struct{}
+
+func (OkHttpFormat) Name() string { return "okhttp" }
+
+func (OkHttpFormat) Detect(input string) bool {
+	for _, line := range strings.Split(input, "\n") {
+		line = okHttpLine(line)
+		if strings.HasPrefix(line, "--> ") && !okHttpEnd.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// okHttpLine strips logger and logcat prefixes from line.
+func okHttpLine(line string) string {
+	line = strings.TrimRight(ansiEscape.ReplaceAllString(line, ""), "\r")
+	if loc := DefaultLogPrefix.FindStringIndex(line); loc != nil {
+		line = line[loc[1]:]
+	}
+	if loc := logcatTag.FindStringIndex(line); loc != nil {
+		line = line[loc[1]:]
+	}
+	return line
+}
+
+func (OkHttpFormat) Parse(input string) (ParsedRequest, *ParsedResponse, error) {
+	pr := ParsedRequest{Headers: textproto.MIMEHeader{}, Params: url.Values{}}
+	var pres *ParsedResponse
+
+	const (
+		before = iota
+		reqHead
+		reqBody
+		between
+		respHead
+		respBody
+		done
+	)
+	state := before
+	var body []string
+	for _, raw := range strings.Split(input, "\n") {
+		line := okHttpLine(raw)
+		trim := strings.TrimSpace(line)
+
+		switch {
+		case state == done:
+		case strings.HasPrefix(line, "--> ") && !okHttpEnd.MatchString(line):
+			if state != before {
+				state = done // the next call
+				continue
+			}
+			// --> POST https://host/path http/1.1  or  --> POST https://host/path (15-byte body)
+			f := strings.Fields(strings.TrimPrefix(line, "--> "))
+			if len(f) < 2 {
+				return pr, nil, fmt.Errorf("invalid OkHttp request line %q", line)
+			}
+			pr.Method, pr.URI = strings.ToUpper(f[0]), f[1]
+			if u, err := url.Parse(pr.URI); err == nil {
+				pr.Params = u.Query()
+			}
+			state = reqHead
+		case strings.HasPrefix(line, "<-- ") && !okHttpEnd.MatchString(line):
+			if state == before {
+				continue
+			}
+			flushBody(&pr.Body, body)
+			body = nil
+			if strings.HasPrefix(line, "<-- HTTP FAILED") {
+				state = done
+				continue
+			}
+			// OkHttp does not log the protocol
+			pres = &ParsedResponse{
+				Proto:   "HTTP/1.1",
+				Status:  okHttpStatus(strings.TrimPrefix(line, "<-- ")),
+				Headers: textproto.MIMEHeader{},
+			}
+			state = respHead
+		case okHttpEnd.MatchString(line):
+			if strings.HasPrefix(line, "-->") {
+				flushBody(&pr.Body, body)
+				state = between
+			} else if pres != nil {
+				flushBody(&pres.Body, body)
+				state = done
+			}
+			body = nil
+		case state == reqHead || state == respHead:
+			h := pr.Headers
+			if state == respHead {
+				h = pres.Headers
+			}
+			name, value, ok := strings.Cut(line, ": ")
+			if ok && name != "" && !strings.ContainsAny(name, " {[\"") {
+				h.Add(textproto.CanonicalMIMEHeaderKey(name), value)
+				continue
+			}
+			// OkHttp prints the body right after the headers, after a blank
+			// line in recent versions
+			if state == reqHead {
+				state = reqBody
+			} else {
+				state = respBody
+			}
+			if trim != "" && !okHttpOmitted.MatchString(trim) {
+				body = append(body, line)
+			}
+		case state == reqBody || state == respBody:
+			if !okHttpOmitted.MatchString(trim) {
+				body = append(body, line)
+			}
+		}
+	}
+	if state == reqBody || state == reqHead {
+		flushBody(&pr.Body, body)
+	} else if pres != nil && (state == respBody || state == respHead) {
+		flushBody(&pres.Body, body)
+	}
+
+	if pr.Method == "" || pr.URI == "" {
+		return pr, pres, fmt.Errorf("missing method or URI")
+	}
+	return pr, pres, nil
+}
+
+// flushBody stores the collected body lines, trimmed of surrounding blank
+// lines.
+func flushBody(dst *[]byte, lines []string) {
+	if b := strings.TrimSpace(strings.Join(lines, "\n")); b != "" {
+		*dst = []byte(b)
+	}
+}
+
+// okHttpStatus reads the status from "201 Created https://host/path (12ms)".
+// HTTP/2 responses have no reason phrase.
+func okHttpStatus(s string) string {
+	var parts []string
+	for _, w := range strings.Fields(s) {
+		if strings.Contains(w, "://") || strings.HasPrefix(w, "(") {
+			break
+		}
+		parts = append(parts, w)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"fmt"
+	"net/textproto"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// requestLine matches an HTTP/1.x request line such as "GET /pets HTTP/1.1".
+var requestLine = regexp.MustCompile(`^([A-Z]+) (\S+) HTTP/\d(?:\.\d)?$`)
+
+// RawHTTPFormat is a plain HTTP/1.1 message as seen on the wire or in a
+// packet capture: request line, headers, a blank line and the body,
+// optionally followed by the response in the same shape. Content-Length is
+// not trusted, since pasted messages are often edited; a body runs up to the
+// response's status line or the end of the input.
+type RawHTTPFormat struct{}
+
+func (RawHTTPFormat) Name() string { return "http" }
+
+func (RawHTTPFormat) Detect(input string) bool {
+	first, _, _ := strings.Cut(strings.TrimSpace(input), "\n")
+	return requestLine.MatchString(strings.TrimSpace(first))
+}
+
+func (RawHTTPFormat) Parse(input string) (ParsedRequest, *ParsedResponse, error) {
+	pr := ParsedRequest{Headers: textproto.MIMEHeader{}, Params: url.Values{}}
+	lines := strings.Split(strings.TrimSpace(input), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], "\r")
+	}
+
+	m := requestLine.FindStringSubmatch(lines[0])
+	if m == nil {
+		return pr, nil, fmt.Errorf("invalid request line %q", lines[0])
+	}
+	pr.Method = m[1]
+	i := readRawHeaders(lines, 1, pr.Headers)
+
+	// the response, if any, starts at the first status line after the headers
+	end := len(lines)
+	for j := i; j < len(lines); j++ {
+		if statusLine.MatchString(lines[j]) {
+			end = j
+			break
+		}
+	}
+	if b := strings.TrimSpace(strings.Join(lines[i:end], "\n")); b != "" {
+		pr.Body = []byte(b)
+	}
+
+	target := m[2]
+	u, err := url.Parse(target)
+	if err != nil {
+		return pr, nil, fmt.Errorf("invalid request target: %w", err)
+	}
+	if u.Host == "" {
+		// origin-form: the host comes from the Host header
+		if host := pr.Headers.Get("Host"); host != "" {
+			u.Host = host
+			u.Scheme = "http"
+			if strings.HasSuffix(host, ":443") {
+				u.Scheme = "https"
+			}
+		}
+	}
+	pr.URI = u.String()
+	pr.Params = u.Query()
+
+	var pres *ParsedResponse
+	if end < len(lines) {
+		pres = &ParsedResponse{Headers: textproto.MIMEHeader{}}
+		pres.Proto, pres.Status, _ = strings.Cut(lines[end], " ")
+		pres.Status = strings.TrimSpace(pres.Status)
+		j := readRawHeaders(lines, end+1, pres.Headers)
+		if b := strings.TrimSpace(strings.Join(lines[j:], "\n")); b != "" {
+			pres.Body = []byte(b)
+		}
+	}
+	return pr, pres, nil
+}
+
+// readRawHeaders adds the "Name: value" lines from lines[i] up to the blank
+// line ending them, returning the index of the first body line.
+func readRawHeaders(lines []string, i int, h textproto.MIMEHeader) int {
+	for ; i < len(lines); i++ {
+		if lines[i] == "" {
+			return i + 1
+		}
+		if statusLine.MatchString(lines[i]) {
+			return i
+		}
+		name, value, ok := strings.Cut(lines[i], ":")
+		if !ok {
+			return i
+		}
+		h.Add(textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name)), strings.TrimSpace(value))
+	}
+	return i
+}
@@ -9,6 +9,8 @@ import (
 	"net/http"
 	"net/textproto"
 	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -18,15 +20,100 @@ const (
 	StateNone State = iota
 	StateHeaders
 	StateBody
+	StateResponseStatus
+	StateResponseHeaders
+	StateResponseBody
+	StateRequestParams
+	StateQueryParams
+	StateFormParams
+	StatePathParams
+	StateCookies
+	StateMultiparts
 )
 
+// partSeparator opens each part of a logged multipart request.
+const partSeparator = "------------"
+
+// paramSections maps the request section titles RestAssured logs as
+// name=value lists to their parser state.
+var paramSections = []struct {
+	title string
+	state State
+}{
+	{"Request params:", StateRequestParams},
+	{"Query params:", StateQueryParams},
+	{"Form params:", StateFormParams},
+	{"Path params:", StatePathParams},
+	{"Cookies:", StateCookies},
+}
+
+// statusLine matches the first line RestAssured logs for a response.
+var statusLine = regexp.MustCompile(`^HTTP/\d(?:\.\d)?\s+\d{3}\b`)
+
+// DefaultLogPrefix matches the prefixes loggers and CI consoles put in front
+// of RestAssured's output: ISO and clock timestamps, bracketed thread names
+// or Jenkins timestamps, a level, and a logger name ended by " - " or ":".
+// It consumes spaces but never tabs, so RestAssured's indentation survives.
+var DefaultLogPrefix = regexp.MustCompile(
+	`^(?:(?:\[?\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?\]?` +
+		`|\[?\d{2}:\d{2}:\d{2}(?:[.,]\d+)?\]?` +
+		`|\[[^\]"]*\]` +
+		`|\b(?:TRACE|DEBUG|INFO|WARN|WARNING|ERROR|FATAL)\b) +)+` +
+		`(?:[\w.$]+(?::\d+)?(?: +-|:) +|- +)?`)
+
+// logLevel spots a level in a stripped prefix, which marks the line as the
+// start of a log record rather than a continuation of one.
+var logLevel = regexp.MustCompile(`\b(?:TRACE|DEBUG|INFO|WARN|WARNING|ERROR|FATAL)\b`)
+
+// ansiEscape matches terminal colour codes, common in CI consoles.
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// responseMarker matches the "Response :" line this package's own
+// ResponseString writes ahead of the status line.
+var responseMarker = regexp.MustCompile(`^Response\s*:?$`)
+
 type ParsedRequest struct {
-	Method     string
-	URI        string
-	Headers    textproto.MIMEHeader
-	Params     url.Values
-	Body       []byte
+	Method  string
+	URI     string
+	Proxy   string
+	Headers textproto.MIMEHeader
+	// Params holds the query of URI.
+	Params url.Values
+	Body   []byte
+	// PathParams holds the logged path params, or those matched against the
+	// spec once the request is looked up.
 	PathParams map[string]string
+	// HeaderOrder and PathParamOrder repeat the logged headers and path
+	// params in logged order, which the maps above lose. RequestString
+	// follows them while they still agree with the maps.
+	HeaderOrder    []Param
+	PathParamOrder []Param
+
+	// The remaining sections are kept in logged order.
+	RequestParams []Param
+	QueryParams   []Param
+	FormParams    []Param
+	Cookies       []Param
+	Multiparts    []Multipart
+}
+
+// Param is one name=value entry of a logged section. RestAssured logs
+// valueless params by name alone, which Value "" stands for.
+type Param struct {
+	Name  string
+	Value string
+}
+
+// Multipart is one part of a logged multipart request.
+type Multipart struct {
+	// Disposition is the form of the Content-Disposition, e.g. "form-data".
+	Disposition string
+	ControlName string
+	FileName    string
+	MimeType    string
+	// Headers holds any further part headers in logged order.
+	Headers []Param
+	Content string
 }
 
 type ParsedResponse struct {
@@ -36,74 +123,458 @@ type ParsedResponse struct {
 	Body    []byte
 }
 
+// StatusCode returns the numeric status, or 0 when Status does not start
+// with one.
+func (pres ParsedResponse) StatusCode() int {
+	code, _, _ := strings.Cut(pres.Status, " ")
+	n, err := strconv.Atoi(code)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// ParseLog parses the request section of a RestAssured log.
 func ParseLog(r *bufio.Reader) (ParsedRequest, error) {
-	var pr ParsedRequest
-	pr.Headers = textproto.MIMEHeader{}
-	pr.Params = url.Values{}
-	var bodyLines []string
-	state := StateNone
+	pr, _, err := ParseExchange(r)
+	return pr, err
+}
 
-	for {
-		line, err := r.ReadString('\n')
-		if err != nil && err != io.EOF {
-			return pr, err
+// ParseExchange parses the first request of a RestAssured log and, when the
+// log contains one, the response that came back.
+func ParseExchange(r *bufio.Reader) (ParsedRequest, *ParsedResponse, error) {
+	return NewLogScanner(r).First()
+}
+
+// Exchange is one request/response block of a log. StartLine and EndLine are
+// the 1-based lines of its first and last recognised line.
+type Exchange struct {
+	Request   ParsedRequest
+	Response  *ParsedResponse
+	StartLine int
+	EndLine   int
+	// Err reports a block that lacks its method or URI.
+	Err error
+}
+
+// LogScanner walks a log that may hold many RestAssured blocks, e.g. a CI
+// console, yielding one Exchange at a time in the manner of bufio.Scanner.
+// A block ends where the next "Request method:" line begins; a response body
+// ends at its first blank line, so unrelated output between blocks is
+// skipped.
+//
+// Logger prefixes are stripped from every line. A prefixed line that carries
+// a level starts a new log record: unless it opens a RestAssured section it
+// belongs to other output interleaved with the block and is skipped.
+type LogScanner struct {
+	r       *bufio.Reader
+	prefix  *regexp.Regexp
+	line    int
+	pending *string
+	cur     Exchange
+	err     error
+	eof     bool
+}
+
+func NewLogScanner(r io.Reader) *LogScanner {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &LogScanner{r: br, prefix: DefaultLogPrefix}
+}
+
+// SetPrefix replaces DefaultLogPrefix with re, which should be anchored at
+// the start of the line. A nil re disables prefix stripping. It must be
+// called before the first call to Next.
+func (sc *LogScanner) SetPrefix(re *regexp.Regexp) { sc.prefix = re }
+
+// First parses the first block, as ParseExchange does.
+func (sc *LogScanner) First() (ParsedRequest, *ParsedResponse, error) {
+	if !sc.Next() {
+		empty := ParsedRequest{Headers: textproto.MIMEHeader{}, Params: url.Values{}}
+		if err := sc.Err(); err != nil {
+			return empty, nil, err
+		}
+		return empty, nil, fmt.Errorf("missing method or URI")
+	}
+	ex := sc.Exchange()
+	return ex.Request, ex.Response, ex.Err
+}
+
+// strip removes colour codes and the logger prefix from line, reporting
+// whether the prefix named a level.
+func (sc *LogScanner) strip(line string) (string, bool) {
+	line = ansiEscape.ReplaceAllString(line, "")
+	if sc.prefix == nil {
+		return line, false
+	}
+	loc := sc.prefix.FindStringIndex(line)
+	if loc == nil || loc[1] == 0 {
+		return line, false
+	}
+	return line[loc[1]:], logLevel.MatchString(line[:loc[1]])
+}
+
+// opensSection reports whether trim starts a part of a RestAssured log.
+func opensSection(trim string) bool {
+	if statusLine.MatchString(trim) || responseMarker.MatchString(trim) || sectionState(trim) != StateNone {
+		return true
+	}
+	for _, t := range []string{"Request method:", "Request URI:", "Proxy:", "Headers:", "Multiparts:", "Body:"} {
+		if strings.HasPrefix(trim, t) {
+			return true
 		}
-		trim := strings.TrimSpace(line)
+	}
+	return false
+}
 
+// Next advances to the next block, reporting false at the end of the input
+// or on a read error.
+func (sc *LogScanner) Next() bool {
+	if sc.err != nil {
+		return false
+	}
+	b := newExchangeParser()
+	for {
+		var line string
 		switch {
-		case strings.HasPrefix(trim, "Request method:"):
-			pr.Method = strings.TrimSpace(strings.TrimPrefix(trim, "Request method:"))
-		case strings.HasPrefix(trim, "Request URI:"):
-			pr.URI = strings.TrimSpace(strings.TrimPrefix(trim, "Request URI:"))
-			if u, err := url.Parse(pr.URI); err == nil {
-				pr.Params = u.Query()
+		case sc.pending != nil:
+			line, sc.pending = *sc.pending, nil
+		case sc.eof:
+			return sc.emit(b)
+		default:
+			l, err := sc.r.ReadString('\n')
+			if err != nil && err != io.EOF {
+				sc.err = err
+				return false
 			}
-		case strings.HasPrefix(trim, "Headers:"):
-			rest := strings.TrimSpace(strings.TrimPrefix(trim, "Headers:"))
-			if rest != "" && rest != "<none>" {
-				parts := strings.SplitN(rest, "=", 2)
-				if len(parts) == 2 {
-					key := textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(parts[0]))
-					pr.Headers.Add(key, strings.TrimSpace(parts[1]))
-				}
+			sc.eof = err == io.EOF
+			if l == "" && sc.eof {
+				return sc.emit(b)
 			}
-			state = StateHeaders
-		case strings.HasPrefix(trim, "Body:"):
-			c := strings.TrimSpace(strings.TrimPrefix(trim, "Body:"))
-			if c != "" && c != "<none>" {
-				bodyLines = append(bodyLines, c)
+			sc.line++
+			var record bool
+			if line, record = sc.strip(l); record && !opensSection(strings.TrimSpace(line)) {
+				continue
 			}
-			state = StateBody
-		case state == StateHeaders:
-			if trim == "" {
-				state = StateNone
-			} else {
-				parts := strings.SplitN(trim, "=", 2)
-				if len(parts) == 2 {
-					pr.Headers.Add(
-						textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(parts[0])),
-						strings.TrimSpace(parts[1]),
-					)
-				}
+		}
+
+		if b.startsNext(strings.TrimSpace(line)) {
+			sc.pending = &line
+			return sc.emit(b)
+		}
+		b.feed(line, sc.line)
+	}
+}
+
+// emit publishes b, reporting false if it holds nothing.
+func (sc *LogScanner) emit(b *exchangeParser) bool {
+	if b.start == 0 {
+		return false
+	}
+	sc.cur = b.exchange()
+	return true
+}
+
+// Exchange returns the block found by the last call to Next.
+func (sc *LogScanner) Exchange() Exchange { return sc.cur }
+
+// Err returns the first read error, if any.
+func (sc *LogScanner) Err() error { return sc.err }
+
+// exchangeParser is the line state machine for a single block.
+type exchangeParser struct {
+	pr         ParsedRequest
+	pres       *ParsedResponse
+	bodyLines  []string
+	respLines  []string
+	part       *Multipart
+	inContent  bool
+	state      State
+	start, end int
+}
+
+func newExchangeParser() *exchangeParser {
+	return &exchangeParser{pr: ParsedRequest{Headers: textproto.MIMEHeader{}, Params: url.Values{}}}
+}
+
+// startsNext reports whether the line opens a new block.
+func (b *exchangeParser) startsNext(trim string) bool {
+	return strings.HasPrefix(trim, "Request method:") && (b.pr.Method != "" || b.pres != nil)
+}
+
+func (b *exchangeParser) startResponse() {
+	b.pres = &ParsedResponse{Headers: textproto.MIMEHeader{}}
+	b.state = StateResponseStatus
+}
+
+func (b *exchangeParser) feed(line string, n int) {
+	trim := strings.TrimSpace(line)
+	used := true
+
+	switch {
+	case b.state == StateResponseStatus:
+		if statusLine.MatchString(trim) {
+			b.pres.Proto, b.pres.Status, _ = strings.Cut(trim, " ")
+			b.pres.Status = strings.TrimSpace(b.pres.Status)
+			b.state = StateResponseHeaders
+		} else {
+			used = false
+		}
+	case b.state == StateResponseHeaders:
+		switch {
+		case trim == "":
+			b.state = StateResponseBody
+			used = false
+		case strings.HasPrefix(trim, "Headers:"):
+			addResponseHeader(b.pres.Headers, strings.TrimSpace(strings.TrimPrefix(trim, "Headers:")))
+		case strings.HasPrefix(trim, "Cookies:"), strings.HasPrefix(trim, "Multiparts:"):
+		case strings.HasPrefix(trim, "Body:"):
+			if c := strings.TrimSpace(strings.TrimPrefix(trim, "Body:")); c != "" && c != "<none>" {
+				b.respLines = append(b.respLines, c)
 			}
-		case state == StateBody:
-			if trim == "" || strings.HasPrefix(trim, "Response") {
-				state = StateNone
-			} else {
-				bodyLines = append(bodyLines, trim)
+			b.state = StateResponseBody
+		default:
+			addResponseHeader(b.pres.Headers, trim)
+		}
+	case b.state == StateResponseBody:
+		switch {
+		case trim == "" && len(b.respLines) > 0:
+			b.state = StateNone
+			used = false
+		case len(b.respLines) == 0 && (trim == "" || trim == "Body:" || trim == "<none>"):
+			used = trim != ""
+		default:
+			b.respLines = append(b.respLines, strings.TrimRight(line, "\r\n"))
+		}
+	case statusLine.MatchString(trim):
+		b.startResponse()
+		b.pres.Proto, b.pres.Status, _ = strings.Cut(trim, " ")
+		b.pres.Status = strings.TrimSpace(b.pres.Status)
+		b.state = StateResponseHeaders
+	case responseMarker.MatchString(trim):
+		b.startResponse()
+	case strings.HasPrefix(trim, "Request method:"):
+		b.pr.Method = strings.TrimSpace(strings.TrimPrefix(trim, "Request method:"))
+	case strings.HasPrefix(trim, "Request URI:"):
+		b.pr.URI = strings.TrimSpace(strings.TrimPrefix(trim, "Request URI:"))
+		if u, err := url.Parse(b.pr.URI); err == nil {
+			b.pr.Params = u.Query()
+		}
+	case strings.HasPrefix(trim, "Proxy:"):
+		if p := strings.TrimSpace(strings.TrimPrefix(trim, "Proxy:")); p != "<none>" {
+			b.pr.Proxy = p
+		}
+		b.state = StateNone
+	case sectionState(trim) != StateNone:
+		b.state = sectionState(trim)
+		_, rest, _ := strings.Cut(trim, ":")
+		b.addParam(strings.TrimSpace(rest))
+	case strings.HasPrefix(trim, "Headers:"):
+		rest := strings.TrimSpace(strings.TrimPrefix(trim, "Headers:"))
+		if rest != "" && rest != "<none>" {
+			parts := strings.SplitN(rest, "=", 2)
+			if len(parts) == 2 {
+				b.addHeader(parts[0], parts[1])
 			}
 		}
-
-		if err == io.EOF {
+		b.state = StateHeaders
+	case strings.HasPrefix(trim, "Multiparts:"):
+		b.state = StateMultiparts
+		b.part = nil
+		b.addPartLine(strings.TrimPrefix(strings.TrimLeft(line, " \t"), "Multiparts:"))
+	case strings.HasPrefix(trim, "Body:"):
+		c := strings.TrimSpace(strings.TrimPrefix(trim, "Body:"))
+		if c != "" && c != "<none>" {
+			b.bodyLines = append(b.bodyLines, c)
+		}
+		b.state = StateBody
+	case b.state == StateMultiparts:
+		b.addPartLine(line)
+	case trim == "" && b.state != StateNone && b.state != StateBody:
+		b.state = StateNone
+		used = false
+	case b.state >= StateRequestParams && b.state <= StateCookies || b.state == StateHeaders:
+		// continuation lines are indented; anything else ends the section
+		if line[0] != ' ' && line[0] != '\t' {
+			b.state = StateNone
+			used = false
 			break
 		}
+		if b.state != StateHeaders {
+			b.addParam(trim)
+			break
+		}
+		parts := strings.SplitN(trim, "=", 2)
+		if len(parts) == 2 {
+			b.addHeader(parts[0], parts[1])
+		}
+	case b.state == StateBody:
+		if trim == "" || strings.HasPrefix(trim, "Response") {
+			b.state = StateNone
+			used = false
+		} else {
+			b.bodyLines = append(b.bodyLines, strings.TrimRight(line, "\r\n"))
+		}
+	default:
+		used = false
+	}
+
+	if used {
+		if b.start == 0 {
+			b.start = n
+		}
+		b.end = n
+	}
+}
+
+func (b *exchangeParser) exchange() Exchange {
+	ex := Exchange{Request: b.pr, Response: b.pres, StartLine: b.start, EndLine: b.end}
+	ex.Request.Body = []byte(strings.Join(dedent(b.bodyLines), "\n"))
+	if ex.Response != nil {
+		if ex.Response.Status == "" {
+			ex.Response = nil
+		} else {
+			ex.Response.Body = []byte(strings.TrimSpace(strings.Join(b.respLines, "\n")))
+		}
+	}
+	if ex.Request.Method == "" || ex.Request.URI == "" {
+		ex.Err = fmt.Errorf("missing method or URI")
 	}
+	return ex
+}
+
+// sectionState returns the state for a line opening a name=value section,
+// or StateNone.
+func sectionState(trim string) State {
+	for _, sec := range paramSections {
+		if strings.HasPrefix(trim, sec.title) {
+			return sec.state
+		}
+	}
+	return StateNone
+}
+
+// addParam records one entry of the current name=value section.
+func (b *exchangeParser) addParam(entry string) {
+	if entry == "" || entry == "<none>" {
+		return
+	}
+	name, value, _ := strings.Cut(entry, "=")
+	p := Param{Name: name, Value: value}
+	switch b.state {
+	case StateRequestParams:
+		b.pr.RequestParams = append(b.pr.RequestParams, p)
+	case StateQueryParams:
+		b.pr.QueryParams = append(b.pr.QueryParams, p)
+	case StateFormParams:
+		b.pr.FormParams = append(b.pr.FormParams, p)
+	case StatePathParams:
+		if b.pr.PathParams == nil {
+			b.pr.PathParams = make(map[string]string)
+		}
+		b.pr.PathParams[name] = value
+		b.pr.PathParamOrder = append(b.pr.PathParamOrder, p)
+	case StateCookies:
+		b.pr.Cookies = append(b.pr.Cookies, p)
+	}
+}
 
-	pr.Body = []byte(strings.Join(bodyLines, "\n"))
-	if pr.Method == "" || pr.URI == "" {
-		return pr, fmt.Errorf("missing method or URI")
+// addHeader records one logged request header.
+func (b *exchangeParser) addHeader(name, value string) {
+	name = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name))
+	value = strings.TrimSpace(value)
+	b.pr.Headers.Add(name, value)
+	b.pr.HeaderOrder = append(b.pr.HeaderOrder, Param{Name: name, Value: value})
+}
+
+// addPartLine feeds one line of the Multiparts section. Each part is a
+// separator, its headers, a blank line and its content; RestAssured indents
+// the first content line but not the ones a pretty-printer adds after it.
+func (b *exchangeParser) addPartLine(line string) {
+	line = strings.TrimRight(line, "\r\n")
+	trim := strings.TrimSpace(line)
+	switch {
+	case trim == partSeparator:
+		b.pr.Multiparts = append(b.pr.Multiparts, Multipart{})
+		b.part = &b.pr.Multiparts[len(b.pr.Multiparts)-1]
+		b.inContent = false
+	case b.part == nil:
+	case !b.inContent && trim == "":
+		b.inContent = true
+	case !b.inContent:
+		name, value, _ := strings.Cut(trim, ":")
+		value = strings.TrimSpace(value)
+		switch {
+		case strings.EqualFold(name, "Content-Disposition"):
+			b.part.Disposition, b.part.ControlName, b.part.FileName = parseDisposition(value)
+		case strings.EqualFold(name, "Content-Type"):
+			b.part.MimeType = value
+		default:
+			b.part.Headers = append(b.part.Headers, Param{Name: name, Value: value})
+		}
+	case b.part.Content == "":
+		b.part.Content = strings.TrimLeft(line, "\t")
+	default:
+		b.part.Content += "\n" + line
+	}
+}
+
+// parseDisposition splits RestAssured's "form-data; name = x; filename = y".
+func parseDisposition(v string) (disposition, name, filename string) {
+	fields := strings.Split(v, ";")
+	disposition = strings.TrimSpace(fields[0])
+	for _, f := range fields[1:] {
+		k, val, _ := strings.Cut(f, "=")
+		switch strings.TrimSpace(k) {
+		case "name":
+			name = strings.TrimSpace(val)
+		case "filename":
+			filename = strings.TrimSpace(val)
+		}
+	}
+	return disposition, name, filename
+}
+
+// dedent strips the indentation common to every non-blank line.
+func dedent(lines []string) []string {
+	prefix := ""
+	first := true
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		indent := l[:len(l)-len(strings.TrimLeft(l, " \t"))]
+		if first {
+			prefix, first = indent, false
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = strings.TrimRight(strings.TrimPrefix(l, prefix), " \t")
+	}
+	return out
+}
+
+// addResponseHeader parses a "Name: value" or "Name=value" header line.
+func addResponseHeader(h textproto.MIMEHeader, line string) {
+	if line == "" || line == "<none>" {
+		return
+	}
+	colon, eq := strings.Index(line, ":"), strings.Index(line, "=")
+	sep := colon
+	if sep < 0 || (eq >= 0 && eq < colon) {
+		sep = eq
+	}
+	if sep <= 0 {
+		return
 	}
-	return pr, nil
+	h.Add(textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(line[:sep])), strings.TrimSpace(line[sep+1:]))
 }
 
 func DoRequest(pr ParsedRequest) (ParsedResponse, error) {
@@ -147,65 +618,150 @@ func (Printer) PrettyResponse(pres ParsedResponse) {
 	fmt.Print(ResponseString(pres))
 }
 
+// RequestString renders pr the way RestAssured's request logging does, so a
+// parsed log prints back unchanged. Headers and path params are written in
+// logged order, or sorted by name once their maps no longer match it.
 func RequestString(pr ParsedRequest) string {
 	var out strings.Builder
 	write := func(format string, args ...interface{}) {
 		fmt.Fprintf(&out, format+"\n", args...)
 	}
+	// list writes a section with its first entry after the title and the
+	// rest on continuation lines.
+	list := func(title, sep string, entries []string) {
+		if len(entries) == 0 {
+			write("%s%s<none>", title, sep)
+			return
+		}
+		for i, e := range entries {
+			if i == 0 {
+				write("%s%s%s", title, sep, e)
+			} else {
+				write("\t\t\t\t%s", e)
+			}
+		}
+	}
+	params := func(ps []Param) []string {
+		out := make([]string, len(ps))
+		for i, p := range ps {
+			out[i] = p.Name
+			if p.Value != "" {
+				out[i] += "=" + p.Value
+			}
+		}
+		return out
+	}
 
-	write("Request method: %s", pr.Method)
-	write("Request URI: %s", pr.URI)
-	write("Proxy: <none>")
-
-	// Render captured query params
-	if len(pr.Params) == 0 {
-		write("Request params: <none>")
+	write("Request method:\t%s", pr.Method)
+	write("Request URI:\t%s", pr.URI)
+	if pr.Proxy == "" {
+		write("Proxy:\t\t\t<none>")
 	} else {
-		write("Request params:")
-		for k, vs := range pr.Params {
-			for _, v := range vs {
-				write("    %s=%s", k, v)
-			}
+		write("Proxy:\t\t\t%s", pr.Proxy)
+	}
+	list("Request params:", "\t", params(pr.RequestParams))
+	list("Query params:", "\t", params(pr.QueryParams))
+	list("Form params:", "\t", params(pr.FormParams))
+
+	pathParams := pr.PathParamOrder
+	if !sameParams(pathParams, pr.PathParams) {
+		pathParams = nil
+		for _, k := range sortedKeys(pr.PathParams) {
+			pathParams = append(pathParams, Param{Name: k, Value: pr.PathParams[k]})
 		}
 	}
+	list("Path params:", "\t", params(pathParams))
 
-	write("Form params: <none>")
-	write("Path params: <none>")
+	headers := pr.HeaderOrder
+	if !sameHeaders(headers, pr.Headers) {
+		headers = nil
+		for _, k := range sortedKeys(pr.Headers) {
+			for _, v := range pr.Headers[k] {
+				headers = append(headers, Param{Name: k, Value: v})
+			}
+		}
+	}
+	var headerLines []string
+	for _, h := range headers {
+		headerLines = append(headerLines, h.Name+"="+h.Value)
+	}
+	list("Headers:", "\t\t", headerLines)
+	list("Cookies:", "\t\t", params(pr.Cookies))
 
-	if len(pr.Headers) == 0 {
-		write("Headers: <none>")
+	if len(pr.Multiparts) == 0 {
+		write("Multiparts:\t\t<none>")
 	} else {
-		write("Headers:")
-		for k, vs := range pr.Headers {
-			for _, v := range vs {
-				write("    %s=%s", k, v)
+		out.WriteString("Multiparts:\t\t")
+		for i, mp := range pr.Multiparts {
+			if i > 0 {
+				out.WriteString("\n\t\t\t\t")
+			}
+			out.WriteString(partSeparator)
+			fmt.Fprintf(&out, "\n\t\t\t\tContent-Disposition: %s; name = %s", mp.Disposition, mp.ControlName)
+			if mp.FileName != "" {
+				fmt.Fprintf(&out, "; filename = %s", mp.FileName)
+			}
+			fmt.Fprintf(&out, "\n\t\t\t\tContent-Type: %s", mp.MimeType)
+			for _, h := range mp.Headers {
+				fmt.Fprintf(&out, "\n\t\t\t\t%s: %s", h.Name, h.Value)
 			}
+			fmt.Fprintf(&out, "\n\n\t\t\t\t%s", mp.Content)
 		}
+		out.WriteString("\n")
 	}
 
-	write("Cookies: <none>")
-	write("Multiparts: <none>")
-
 	if len(pr.Body) == 0 {
-		write("Body: <none>")
+		write("Body:\t\t\t<none>")
 	} else {
 		contentType := pr.Headers.Get("Content-Type")
 		write("Body:")
-		if strings.Contains(contentType, "application/json") {
+		if strings.Contains(contentType, "json") {
 			var buf bytes.Buffer
 			if err := json.Indent(&buf, pr.Body, "", "    "); err == nil {
-				write(buf.String())
+				write("%s", buf.String())
 			} else {
-				write(string(pr.Body))
+				write("%s", pr.Body)
 			}
 		} else {
-			write(string(pr.Body))
+			write("%s", pr.Body)
 		}
 	}
 
 	return out.String()
 }
 
+// sameParams reports whether ps lists exactly the entries of m.
+func sameParams(ps []Param, m map[string]string) bool {
+	if len(ps) != len(m) {
+		return false
+	}
+	for _, p := range ps {
+		if v, ok := m[p.Name]; !ok || v != p.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// sameHeaders reports whether ps lists exactly the values of h, with each
+// name's values in the same order.
+func sameHeaders(ps []Param, h textproto.MIMEHeader) bool {
+	seen := make(map[string]int, len(h))
+	for _, p := range ps {
+		vs := h[p.Name]
+		if seen[p.Name] >= len(vs) || vs[seen[p.Name]] != p.Value {
+			return false
+		}
+		seen[p.Name]++
+	}
+	for k, vs := range h {
+		if seen[k] != len(vs) {
+			return false
+		}
+	}
+	return true
+}
+
 // ResponseString builds a string representation of the ParsedResponse.
 func ResponseString(pres ParsedResponse) string {
 	var out strings.Builder
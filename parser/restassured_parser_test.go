@@ -2,8 +2,12 @@ package parser
 
 import (
 	"bufio"
+	"bytes"
 	"net/textproto"
+	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
 	"strings"
 	"testing"
 )
@@ -45,3 +49,279 @@ func TestParseLog(t *testing.T) {
 		t.Errorf("Expected Body %q, got %q", expectedBody, string(pr.Body))
 	}
 }
+
+const mockRestAssuredExchange = `Request method:	GET
+Request URI:	http://example.com/api/pets/7
+Headers:	Accept=application/json
+Body:	<none>
+HTTP/1.1 404 Not Found
+Content-Type: application/json
+X-Trace: a=b
+
+{
+    "error": "not found"
+}
+`
+
+func TestParseExchange(t *testing.T) {
+	pr, pres, err := ParseExchange(bufio.NewReader(strings.NewReader(mockRestAssuredExchange)))
+	if err != nil {
+		t.Fatalf("ParseExchange returned error: %v", err)
+	}
+	if pr.Method != "GET" || pr.URI != "http://example.com/api/pets/7" {
+		t.Errorf("unexpected request %s %s", pr.Method, pr.URI)
+	}
+	if len(pr.Body) != 0 {
+		t.Errorf("expected empty request body, got %q", pr.Body)
+	}
+	if pres == nil {
+		t.Fatal("expected a parsed response")
+	}
+	if pres.Proto != "HTTP/1.1" || pres.Status != "404 Not Found" || pres.StatusCode() != 404 {
+		t.Errorf("unexpected status line %q %q", pres.Proto, pres.Status)
+	}
+	expectedHeaders := textproto.MIMEHeader{
+		"Content-Type": {"application/json"},
+		"X-Trace":      {"a=b"},
+	}
+	if !reflect.DeepEqual(pres.Headers, expectedHeaders) {
+		t.Errorf("Headers mismatch.\nExpected: %#v\nGot:      %#v", expectedHeaders, pres.Headers)
+	}
+	expectedBody := "{\n    \"error\": \"not found\"\n}"
+	if string(pres.Body) != expectedBody {
+		t.Errorf("Expected Body %q, got %q", expectedBody, pres.Body)
+	}
+}
+
+func TestParseExchangeRoundTrip(t *testing.T) {
+	want := ParsedResponse{
+		Proto:   "HTTP/1.1",
+		Status:  "201 Created",
+		Headers: textproto.MIMEHeader{"Location": {"/pets/8"}},
+		Body:    []byte("created"),
+	}
+	log := mockRestAssuredLog + ResponseString(want)
+	pr, pres, err := ParseExchange(bufio.NewReader(strings.NewReader(log)))
+	if err != nil {
+		t.Fatalf("ParseExchange returned error: %v", err)
+	}
+	if string(pr.Body) != `{"key":"value"}` {
+		t.Errorf("request body changed: %q", pr.Body)
+	}
+	if pres == nil || !reflect.DeepEqual(*pres, want) {
+		t.Errorf("Response mismatch.\nExpected: %#v\nGot:      %#v", want, pres)
+	}
+
+	if _, pres, _ := ParseExchange(bufio.NewReader(strings.NewReader(mockRestAssuredLog))); pres != nil {
+		t.Errorf("expected no response, got %#v", pres)
+	}
+}
+
+const mockConsoleLog = `[INFO] Running UserTests
+Request method:	GET
+Request URI:	http://example.com/users/1
+Headers:		Accept=*/*
+
+HTTP/1.1 200 OK
+Content-Type: application/json
+
+{"id":1}
+
+[INFO] Tests run: 1
+Request method:	DELETE
+Request URI:	http://example.com/users/2
+Request method:	POST
+Request URI:	http://example.com/users
+Body:
+    {"name":"x"}
+HTTP/1.1 201 Created
+Location: /users/3
+`
+
+func TestLogScanner(t *testing.T) {
+	sc := NewLogScanner(strings.NewReader(mockConsoleLog))
+	var got []Exchange
+	for sc.Next() {
+		got = append(got, sc.Exchange())
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 exchanges, got %d", len(got))
+	}
+
+	want := []struct {
+		method, uri, status string
+		start, end          int
+	}{
+		{"GET", "http://example.com/users/1", "200 OK", 2, 9},
+		{"DELETE", "http://example.com/users/2", "", 12, 13},
+		{"POST", "http://example.com/users", "201 Created", 14, 19},
+	}
+	for i, w := range want {
+		ex := got[i]
+		if ex.Err != nil {
+			t.Errorf("exchange %d: unexpected error %v", i, ex.Err)
+		}
+		if ex.Request.Method != w.method || ex.Request.URI != w.uri {
+			t.Errorf("exchange %d: got %s %s", i, ex.Request.Method, ex.Request.URI)
+		}
+		status := ""
+		if ex.Response != nil {
+			status = ex.Response.Status
+		}
+		if status != w.status {
+			t.Errorf("exchange %d: expected status %q, got %q", i, w.status, status)
+		}
+		if ex.StartLine != w.start || ex.EndLine != w.end {
+			t.Errorf("exchange %d: expected lines %d-%d, got %d-%d", i, w.start, w.end, ex.StartLine, ex.EndLine)
+		}
+	}
+	if body := string(got[0].Response.Body); body != `{"id":1}` {
+		t.Errorf("Expected first response body {\"id\":1}, got %q", body)
+	}
+	if body := string(got[2].Request.Body); body != `{"name":"x"}` {
+		t.Errorf("Expected POST body, got %q", body)
+	}
+}
+
+// TestRequestStringGolden checks that RestAssured's own request logs in
+// testdata parse and print back byte for byte.
+func TestRequestStringGolden(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.log")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no golden files: %v", err)
+	}
+	for _, f := range files {
+		t.Run(filepath.Base(f), func(t *testing.T) {
+			want, err := os.ReadFile(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pr, err := ParseLog(bufio.NewReader(bytes.NewReader(want)))
+			if err != nil {
+				t.Fatalf("ParseLog returned error: %v", err)
+			}
+			if got := RequestString(pr); got != string(want) {
+				t.Errorf("round trip mismatch.\nExpected:\n%s\nGot:\n%s", want, got)
+			}
+		})
+	}
+}
+
+func TestParseLogSections(t *testing.T) {
+	data, err := os.ReadFile("testdata/multipart.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr, err := ParseLog(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatalf("ParseLog returned error: %v", err)
+	}
+
+	if pr.Proxy != "http://proxy.internal:3128" {
+		t.Errorf("Expected proxy, got %q", pr.Proxy)
+	}
+	if want := []Param{{"verbose", "true"}, {"debug", ""}}; !reflect.DeepEqual(pr.RequestParams, want) {
+		t.Errorf("RequestParams mismatch: %#v", pr.RequestParams)
+	}
+	if want := []Param{{"trace", "on"}}; !reflect.DeepEqual(pr.QueryParams, want) {
+		t.Errorf("QueryParams mismatch: %#v", pr.QueryParams)
+	}
+	if want := map[string]string{"petId": "7"}; !reflect.DeepEqual(pr.PathParams, want) {
+		t.Errorf("PathParams mismatch: %#v", pr.PathParams)
+	}
+	if want := []Param{{"JSESSIONID", "8F1C2;Path=/;HttpOnly"}}; !reflect.DeepEqual(pr.Cookies, want) {
+		t.Errorf("Cookies mismatch: %#v", pr.Cookies)
+	}
+
+	want := []Multipart{
+		{Disposition: "form-data", ControlName: "file", FileName: "dog.png", MimeType: "application/octet-stream", Content: "<inputstream>"},
+		{
+			Disposition: "form-data", ControlName: "metadata", FileName: "file", MimeType: "application/json",
+			Headers: []Param{{"X-Part-Id", "2"}},
+			Content: "{\n    \"tags\": [\n        \"good\"\n    ]\n}",
+		},
+	}
+	if !reflect.DeepEqual(pr.Multiparts, want) {
+		t.Errorf("Multiparts mismatch.\nExpected: %#v\nGot:      %#v", want, pr.Multiparts)
+	}
+	if len(pr.Body) != 0 {
+		t.Errorf("expected no body, got %q", pr.Body)
+	}
+}
+
+const mockLogbackLog = `12:01:02.120 [main] INFO  c.e.PetTests - creating pet
+12:01:02.123 [main] INFO - Request method:	POST
+Request URI:	http://example.com/pets
+Headers:		Accept=*/*
+				Content-Type=application/json
+12:01:02.124 [pool-2-thread-1] DEBUG o.a.h.wire - >> "POST /pets HTTP/1.1"
+Body:
+{
+    "name": "rex"
+}
+12:01:02.200 [main] INFO  io.restassured.Log: HTTP/1.1 201 Created
+Location: /pets/9
+`
+
+const mockJenkinsLog = "[2024-05-01T12:01:02.123Z] \x1b[32mRequest method:\tGET\x1b[0m\n" +
+	"[2024-05-01T12:01:02.123Z] Request URI:\thttp://example.com/pets?limit=2\n" +
+	"[2024-05-01T12:01:02.123Z] Headers:\t\tAccept=*/*\n" +
+	"[2024-05-01T12:01:02.123Z] \t\t\t\tX-Trace=1\n" +
+	"[2024-05-01T12:01:02.124Z] Downloading artifacts\n" +
+	"[2024-05-01T12:01:02.125Z] Body:\t\t\t<none>\n"
+
+func TestParseExchangePrefixed(t *testing.T) {
+	pr, pres, err := ParseExchange(bufio.NewReader(strings.NewReader(mockLogbackLog)))
+	if err != nil {
+		t.Fatalf("ParseExchange returned error: %v", err)
+	}
+	if pr.Method != "POST" || pr.URI != "http://example.com/pets" {
+		t.Errorf("unexpected request %s %s", pr.Method, pr.URI)
+	}
+	expectedHeaders := textproto.MIMEHeader{
+		"Accept":       {"*/*"},
+		"Content-Type": {"application/json"},
+	}
+	if !reflect.DeepEqual(pr.Headers, expectedHeaders) {
+		t.Errorf("Headers mismatch.\nExpected: %#v\nGot:      %#v", expectedHeaders, pr.Headers)
+	}
+	if string(pr.Body) != "{\n    \"name\": \"rex\"\n}" {
+		t.Errorf("unexpected body %q", pr.Body)
+	}
+	if pres == nil || pres.Status != "201 Created" || pres.Headers.Get("Location") != "/pets/9" {
+		t.Errorf("unexpected response %#v", pres)
+	}
+
+	pr, err = ParseLog(bufio.NewReader(strings.NewReader(mockJenkinsLog)))
+	if err != nil {
+		t.Fatalf("ParseLog returned error: %v", err)
+	}
+	if pr.Method != "GET" || pr.URI != "http://example.com/pets?limit=2" {
+		t.Errorf("unexpected request %s %s", pr.Method, pr.URI)
+	}
+	if pr.Headers.Get("X-Trace") != "1" {
+		t.Errorf("continuation header lost: %#v", pr.Headers)
+	}
+}
+
+func TestLogScannerSetPrefix(t *testing.T) {
+	log := "build-7 | Request method: DELETE\nbuild-7 | Request URI: http://example.com/pets/1\n"
+
+	sc := NewLogScanner(strings.NewReader(log))
+	if _, _, err := sc.First(); err == nil {
+		t.Error("expected the default prefix to miss a custom one")
+	}
+
+	sc = NewLogScanner(strings.NewReader(log))
+	sc.SetPrefix(regexp.MustCompile(`^build-\d+ \| `))
+	pr, _, err := sc.First()
+	if err != nil {
+		t.Fatalf("First returned error: %v", err)
+	}
+	if pr.Method != "DELETE" || pr.URI != "http://example.com/pets/1" {
+		t.Errorf("unexpected request %s %s", pr.Method, pr.URI)
+	}
+}
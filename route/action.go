@@ -1,53 +1,160 @@
 package route
 
 import (
-	"better-docs/indexing"
-	"better-docs/parser"
-	"bufio"
 	"bytes"
-	"github.com/blevesearch/bleve/v2"
+	"crypto/tls"
+	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"better-docs/indexing"
+	"better-docs/parser"
+	"github.com/blevesearch/bleve/v2"
 )
 
+// ActionService re-sends logged requests. Calls go through the proxy, so they
+// get the same guard, TLS, auth, validation, fault and recording settings as
+// the try-it panel, and only reach hosts of a registered spec.
 type ActionService struct {
 	Registry indexing.Registry
 	Index    bleve.Index
+	Proxy    *ProxyService
+	// LogPrefix overrides the parser's default RestAssured prefix pattern,
+	// as SearchService.LogPrefix does.
+	LogPrefix *regexp.Regexp
 }
 
-func NewActionService(reg indexing.Registry, idx bleve.Index) *ActionService {
+func NewActionService(reg indexing.Registry, idx bleve.Index, proxy *ProxyService) *ActionService {
 	return &ActionService{
 		Registry: reg,
 		Index:    idx,
+		Proxy:    proxy,
 	}
 }
 
+// ActionHandler replays a logged request against its spec's upstream. The
+// environment defaults to the one owning the logged host; ?_env= or the
+// X-Docs-Env header sends it to another, e.g. a staging log replayed on dev.
+// The response is printed the way RestAssured logs it, or returned as an
+// actionResult with timings when the caller accepts application/json.
+//
+// POST /action[?_env=...][&prefix=...]
 func (s *ActionService) ActionHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		env := selectedEnv(r)
 		data, err := io.ReadAll(r.Body)
 		if err != nil {
 			http.Error(w, "failed to read body: "+err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		pr, err := parser.ParseLog(bufio.NewReader(bytes.NewReader(data)))
+		format, err := detectFormat(r, string(data), s.LogPrefix)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		pr, _, err := format.Parse(string(data))
 		if err != nil {
 			http.Error(w, "parse error: "+err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		_, _, _, err = indexing.FindOperation(s.Index, s.Registry, pr.Method, pr.URI)
+		specName, opID, pathParams, err := indexing.FindOperation(s.Index, s.Registry, pr.Method, pr.URI)
 		if err != nil {
 			http.Error(w, "no match: "+err.Error(), http.StatusNotFound)
 			return
 		}
+		pr.URI = indexing.ResolveURL(s.Registry, specName, pr.URI)
+
+		target, err := url.Parse(pr.URI)
+		if err != nil {
+			http.Error(w, "invalid target url: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		// upstreamForURL rewrites the host for an environment override;
+		// the proxy does that itself, so check a copy
+		check := *target
+		if s.Proxy.upstreamForURL(&check, env) == nil {
+			msg := fmt.Sprintf("no upstream for host %s", target.Host)
+			if env != "" {
+				msg += fmt.Sprintf(" in environment %q", env)
+			}
+			http.Error(w, msg, http.StatusForbidden)
+			return
+		}
 
-		response, err := parser.DoRequest(pr)
+		q := url.Values{"url": {target.String()}}
+		if env != "" {
+			q.Set(envQueryParam, env)
+		}
+		req, err := http.NewRequestWithContext(r.Context(), pr.Method, "/api?"+q.Encode(), bytes.NewReader(pr.Body))
 		if err != nil {
-			http.Error(w, "request error: "+err.Error(), http.StatusInternalServerError)
+			http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Header = http.Header(pr.Headers).Clone()
+		if req.Header == nil {
+			req.Header = http.Header{}
+		}
+		// the caller's session and origin still apply to the replayed call,
+		// not whatever the logged request carried
+		for _, c := range r.Cookies() {
+			req.AddCookie(c)
+		}
+		req.Host = r.Host
+		req.Header.Del("Origin")
+		if o := r.Header.Get("Origin"); o != "" {
+			req.Header.Set("Origin", o)
+		}
+
+		trace := &actionTrace{}
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace.clientTrace()))
+		buf := newResponseBuffer()
+		trace.start = time.Now()
+		s.Proxy.ProxyHandler()(buf, req)
+		timings := trace.timings(time.Now())
+
+		// the proxy gives up without writing when the call is cancelled,
+		// e.g. by the client leaving during an injected delay
+		if buf.status == 0 {
+			if err := r.Context().Err(); err != nil {
+				http.Error(w, "upstream call abandoned: "+err.Error(), http.StatusGatewayTimeout)
+			} else {
+				http.Error(w, "upstream call returned no response", http.StatusBadGateway)
+			}
+			return
+		}
+
+		if strings.Contains(r.Header.Get("Accept"), "application/json") {
+			body, encoding := harText(buf.body.Bytes())
+			writeJSON(w, http.StatusOK, actionResult{
+				Spec:         specName,
+				OperationID:  opID,
+				PathParams:   pathParams,
+				URL:          target.String(),
+				Status:       buf.status,
+				Headers:      buf.header,
+				Body:         body,
+				BodyEncoding: encoding,
+				Timings:      timings,
+			})
 			return
 		}
 
+		// the proxy does not pass on the upstream protocol
+		response := parser.ParsedResponse{
+			Proto:   "HTTP/1.1",
+			Status:  fmt.Sprintf("%d %s", buf.status, http.StatusText(buf.status)),
+			Headers: textproto.MIMEHeader(buf.header),
+			Body:    buf.body.Bytes(),
+		}
 		responseString := parser.ResponseString(response)
 
 		w.Header().Set("Content-Type", "text/plain")
@@ -59,3 +166,110 @@ func (s *ActionService) ActionHandler() http.HandlerFunc {
 
 	}
 }
+
+// responseBuffer collects what a handler writes so it can be reformatted.
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{header: http.Header{}}
+}
+
+func (b *responseBuffer) Header() http.Header { return b.header }
+
+func (b *responseBuffer) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	return b.body.Write(p)
+}
+
+// actionResult is the JSON form of a replayed call. The body is base64
+// encoded when it is not UTF-8, as in HAR.
+type actionResult struct {
+	Spec         string            `json:"spec"`
+	OperationID  string            `json:"operationId"`
+	PathParams   map[string]string `json:"pathParams,omitempty"`
+	URL          string            `json:"url"`
+	Status       int               `json:"status"`
+	Headers      http.Header       `json:"headers"`
+	Body         string            `json:"body"`
+	BodyEncoding string            `json:"bodyEncoding,omitempty"`
+	Timings      actionTimings     `json:"timings"`
+}
+
+// actionTimings are in milliseconds. DNS, connect and TLS are zero when a
+// pooled connection was reused. TTFB and total count from when the call
+// entered the proxy, so they include credential lookups and failover.
+type actionTimings struct {
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	TLS     float64 `json:"tls"`
+	TTFB    float64 `json:"ttfb"`
+	Total   float64 `json:"total"`
+}
+
+// actionTrace records the phases of the upstream call. Hooks may run on the
+// transport's dialing goroutines, hence the lock.
+type actionTrace struct {
+	mu                         sync.Mutex
+	start, dnsStart, connStart time.Time
+	tlsStart, firstByte        time.Time
+	dns, connect, tls          time.Duration
+}
+
+func (t *actionTrace) clientTrace() *httptrace.ClientTrace {
+	lock := func(f func()) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		f()
+	}
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { lock(func() { t.dnsStart = time.Now() }) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { lock(func() { t.dns = time.Since(t.dnsStart) }) },
+		ConnectStart: func(string, string) {
+			lock(func() {
+				if t.connStart.IsZero() {
+					t.connStart = time.Now()
+				}
+			})
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				lock(func() { t.connect = time.Since(t.connStart) })
+			}
+		},
+		TLSHandshakeStart: func() { lock(func() { t.tlsStart = time.Now() }) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { lock(func() { t.tls = time.Since(t.tlsStart) }) },
+		GotFirstResponseByte: func() {
+			lock(func() {
+				if t.firstByte.IsZero() {
+					t.firstByte = time.Now()
+				}
+			})
+		},
+	}
+}
+
+func (t *actionTrace) timings(end time.Time) actionTimings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ms := func(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+	out := actionTimings{
+		DNS:     ms(t.dns),
+		Connect: ms(t.connect),
+		TLS:     ms(t.tls),
+		Total:   ms(end.Sub(t.start)),
+	}
+	if !t.firstByte.IsZero() {
+		out.TTFB = ms(t.firstByte.Sub(t.start))
+	}
+	return out
+}
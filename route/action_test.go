@@ -0,0 +1,137 @@
+package route
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"better-docs/indexing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActionReplaysThroughProxy(t *testing.T) {
+	seen := make(chan *http.Request, 1)
+	backend := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen <- r
+			_, _ = io.WriteString(w, name)
+		}))
+	}
+	dev, staging := backend("dev"), backend("staging")
+	defer dev.Close()
+	defer staging.Close()
+
+	dir := t.TempDir()
+	doc := `{"openapi":"3.0.0","info":{"title":"Pets","version":"1"},
+	  "servers":[{"url":"` + staging.URL + `/v1"}],
+	  "paths":{"/pets/{id}":{"get":{"operationId":"getPet",
+	    "parameters":[{"name":"id","in":"path","required":true,"schema":{"type":"string"}}],
+	    "responses":{"200":{"description":"OK"}}}}}}`
+	specPath := filepath.Join(dir, "pets.json")
+	require.NoError(t, os.WriteFile(specPath, []byte(doc), 0o644))
+	cfg, _ := json.Marshal([]indexing.SpecConfig{{DisplayName: "Pets", Name: "pets", File: specPath}})
+	cfgPath := filepath.Join(dir, "specs.json")
+	require.NoError(t, os.WriteFile(cfgPath, cfg, 0o644))
+	reg, err := indexing.LoadConfigAndIndex(context.Background(), cfgPath, filepath.Join(dir, "cache.gob"))
+	require.NoError(t, err)
+	idx, err := indexing.BuildShardedIndices(filepath.Join(dir, "bleve"), indexing.NewIndexMapping(), reg)
+	require.NoError(t, err)
+
+	specs := []Spec{{
+		Name:    "pets",
+		Headers: map[string]string{"X-Tenant": "acme"},
+		Environments: []Environment{
+			{Name: "dev", ProxyBase: dev.URL + "/v1"},
+			{Name: "staging", ProxyBase: staging.URL + "/v1"},
+		},
+		DefaultEnvironment: "dev",
+	}}
+	upstreams, err := buildUpstreams(specs)
+	require.NoError(t, err)
+	guard, err := NewGuard(GuardConfig{}, AllowedHosts(specs, reg))
+	require.NoError(t, err)
+	proxy := NewProxyService(upstreams, nil, http.DefaultClient, guard, nil, nil, nil, nil)
+	h := NewActionService(reg, idx, proxy).ActionHandler()
+
+	// no Host header, so the path resolves against the spec's server
+	log := "GET /v1/pets/7 HTTP/1.1\nAccept: text/plain\n"
+	call := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodPost, target, strings.NewReader(log)))
+		return rec
+	}
+
+	rec := call("/action")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Body.String(), "200 OK")
+	require.Contains(t, rec.Body.String(), "staging")
+	up := <-seen
+	require.Equal(t, "/v1/pets/7", up.URL.Path)
+	require.Equal(t, "acme", up.Header.Get("X-Tenant"))
+
+	rec = call("/action?_env=dev")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Body.String(), "dev")
+	require.Equal(t, "/v1/pets/7", (<-seen).URL.Path)
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/action", strings.NewReader(log))
+	req.Header.Set("Accept", "application/json")
+	h(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	<-seen
+	var res actionResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Equal(t, "pets", res.Spec)
+	require.Equal(t, "getPet", res.OperationID)
+	require.Equal(t, map[string]string{"id": "7"}, res.PathParams)
+	require.Equal(t, staging.URL+"/v1/pets/7", res.URL)
+	require.Equal(t, http.StatusOK, res.Status)
+	require.Equal(t, "staging", res.Body)
+	require.Empty(t, res.BodyEncoding)
+	require.Positive(t, res.Timings.Total)
+	require.Positive(t, res.Timings.TTFB)
+	require.LessOrEqual(t, res.Timings.TTFB, res.Timings.Total)
+
+	// RestAssured logs honour the prefix pattern, as in /raSearch
+	ra := ">> Request method:\tGET\n>> Request URI:\t" + staging.URL + "/v1/pets/7\n>> Headers:\t\tAccept=*/*\n"
+	rec = httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/action?prefix=^%3E%3E+", strings.NewReader(ra)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, "/v1/pets/7", (<-seen).URL.Path)
+
+	// a call that ends without a response, here a client leaving during an
+	// injected delay, is a gateway error rather than an empty success
+	proxy.Faults = NewFaultInjector(NewDocuments(specs))
+	faultRec := httptest.NewRecorder()
+	proxy.Faults.FaultsHandler()(faultRec, httptest.NewRequest(http.MethodPost, "/faults",
+		strings.NewReader(`{"session":"slow","latency":"5s"}`)))
+	require.Equal(t, http.StatusCreated, faultRec.Code)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req = httptest.NewRequest(http.MethodPost, "/action", strings.NewReader(log+faultSessionHeader+": slow\n")).WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	rec = httptest.NewRecorder()
+	h(rec, req)
+	require.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	require.Empty(t, seen)
+	proxy.Faults = nil
+
+	rec = call("/action?_env=prod")
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	// a spec without a matching upstream is not reachable
+	upstreams, err = buildUpstreams([]Spec{{Name: "pets", ProxyBase: dev.URL}})
+	require.NoError(t, err)
+	proxy.Upstreams = upstreams
+	rec = call("/action")
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.Empty(t, seen)
+}
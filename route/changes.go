@@ -0,0 +1,224 @@
+package route
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"better-docs/indexing"
+)
+
+type ChangesService struct {
+	Dir string
+}
+
+func NewChangesService(dir string) *ChangesService {
+	return &ChangesService{Dir: dir}
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title   string   `xml:"title"`
+	ID      string   `xml:"id"`
+	Updated string   `xml:"updated"`
+	Link    atomLink `xml:"link"`
+	Content atomText `xml:"content"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Description string  `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// ChangesHandler serves the spec changelog as JSON, Atom or RSS.
+//
+// Query params: spec (repeatable), tag (repeatable), limit, format=json|atom|rss.
+// Without format the Accept header decides, defaulting to JSON.
+func (s *ChangesService) ChangesHandler() http.HandlerFunc {
+	type change struct {
+		indexing.ChangeEntry
+		Summary string `json:"summary"`
+		Text    string `json:"text"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		entries, err := indexing.LoadChangelog(s.Dir)
+		if err != nil {
+			http.Error(w, "changelog error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		q := r.URL.Query()
+		entries = filterChanges(entries, q["spec"], q["tag"])
+		if ls := q.Get("limit"); ls != "" {
+			if n, err := strconv.Atoi(ls); err == nil && n >= 0 && n < len(entries) {
+				entries = entries[:n]
+			}
+		}
+
+		self := requestBaseURL(r) + r.URL.RequestURI()
+
+		switch changesFormat(r) {
+		case "atom":
+			w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+			writeXML(w, buildAtom(entries, self, requestBaseURL(r)))
+		case "rss":
+			w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+			writeXML(w, buildRSS(entries, self, requestBaseURL(r)))
+		default:
+			out := make([]change, 0, len(entries))
+			for _, e := range entries {
+				out = append(out, change{e, e.Summary(), e.Text()})
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(out); err != nil {
+				http.Error(w, "failed to write response: "+err.Error(), http.StatusInternalServerError)
+			}
+		}
+	}
+}
+
+func filterChanges(entries []indexing.ChangeEntry, specs, tags []string) []indexing.ChangeEntry {
+	want := make(map[string]struct{}, len(specs))
+	for _, s := range specs {
+		want[strings.ToLower(s)] = struct{}{}
+	}
+	out := make([]indexing.ChangeEntry, 0, len(entries))
+	for _, e := range entries {
+		if len(want) > 0 {
+			if _, ok := want[strings.ToLower(e.SpecName)]; !ok {
+				continue
+			}
+		}
+		e = e.FilterTags(tags)
+		if e.Empty() {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func changesFormat(r *http.Request) string {
+	if f := strings.ToLower(r.URL.Query().Get("format")); f != "" {
+		return f
+	}
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, "application/atom+xml"):
+		return "atom"
+	case strings.Contains(accept, "application/rss+xml"):
+		return "rss"
+	}
+	return "json"
+}
+
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+func changeID(e indexing.ChangeEntry) string {
+	return fmt.Sprintf("urn:better-docs:change:%s:%s", e.SpecName, e.Hash)
+}
+
+func buildAtom(entries []indexing.ChangeEntry, self, base string) atomFeed {
+	updated := time.Now().UTC()
+	if len(entries) > 0 {
+		updated = entries[0].Time
+	}
+	feed := atomFeed{
+		Title:   "API changes",
+		ID:      self,
+		Updated: updated.Format(time.RFC3339),
+		Link:    atomLink{Href: self, Rel: "self"},
+	}
+	for _, e := range entries {
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:   e.Summary(),
+			ID:      changeID(e),
+			Updated: e.Time.Format(time.RFC3339),
+			Link:    atomLink{Href: base + "/specs/" + e.SpecName + "/"},
+			Content: atomText{Type: "text", Body: e.Text()},
+		})
+	}
+	return feed
+}
+
+func buildRSS(entries []indexing.ChangeEntry, self, base string) rssFeed {
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       "API changes",
+			Link:        self,
+			Description: "Operations added, removed or changed across specs",
+		},
+	}
+	for _, e := range entries {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       e.Summary(),
+			Link:        base + "/specs/" + e.SpecName + "/",
+			GUID:        rssGUID{Value: changeID(e)},
+			PubDate:     e.Time.Format(time.RFC1123Z),
+			Description: e.Text(),
+		})
+	}
+	return feed
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) {
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		http.Error(w, "failed to write response: "+err.Error(), http.StatusInternalServerError)
+	}
+}
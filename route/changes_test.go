@@ -0,0 +1,17 @@
+package route
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"better-docs/indexing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRSSGUIDIsNotPermalink(t *testing.T) {
+	entries := []indexing.ChangeEntry{{SpecName: "pets", Hash: "abc", Time: time.Unix(0, 0).UTC()}}
+	out, err := xml.Marshal(buildRSS(entries, "http://docs.test/changes?format=rss", "http://docs.test"))
+	require.NoError(t, err)
+	require.Contains(t, string(out), `<guid isPermaLink="false">urn:better-docs:change:pets:abc</guid>`)
+}
@@ -0,0 +1,281 @@
+package route
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuthConfig declares how the proxy authenticates to a spec's upstream.
+// Secret values never live in specs.json; Secret and friends name an entry
+// looked up in the environment or the secrets file.
+type AuthConfig struct {
+	Type string `json:"type"` // header, basic, apiKey or oauth2
+
+	// header / apiKey
+	Name   string `json:"name,omitempty"`
+	In     string `json:"in,omitempty"` // apiKey only: header (default) or query
+	Prefix string `json:"prefix,omitempty"`
+
+	// basic
+	Username string `json:"username,omitempty"`
+
+	// oauth2 client-credentials
+	TokenURL string   `json:"tokenUrl,omitempty"`
+	ClientID string   `json:"clientId,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"`
+	Audience string   `json:"audience,omitempty"`
+
+	// Secret names the header value, password, API key or client secret.
+	Secret string `json:"secret,omitempty"`
+}
+
+// SecretStore resolves secret names from the environment first, then from
+// an optional JSON file of name → value pairs.
+type SecretStore struct {
+	file map[string]string
+}
+
+func LoadSecretStore(path string) (*SecretStore, error) {
+	s := &SecretStore{file: map[string]string{}}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read secrets file: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.file); err != nil {
+		return nil, fmt.Errorf("decode secrets file: %w", err)
+	}
+	return s, nil
+}
+
+func (s *SecretStore) Lookup(name string) (string, bool) {
+	if v, ok := os.LookupEnv(name); ok {
+		return v, true
+	}
+	v, ok := s.file[name]
+	return v, ok
+}
+
+// Authenticator adds credentials to an outgoing upstream request.
+type Authenticator interface {
+	Apply(ctx context.Context, req *http.Request) error
+	// Invalidate drops cached tokens after the upstream rejected them.
+	Invalidate()
+}
+
+type headerAuth struct {
+	name, value string
+}
+
+func (a headerAuth) Apply(_ context.Context, req *http.Request) error {
+	req.Header.Set(a.name, a.value)
+	return nil
+}
+
+func (headerAuth) Invalidate() {}
+
+type basicAuth struct {
+	username, password string
+}
+
+func (a basicAuth) Apply(_ context.Context, req *http.Request) error {
+	req.SetBasicAuth(a.username, a.password)
+	return nil
+}
+
+func (basicAuth) Invalidate() {}
+
+type queryAuth struct {
+	name, value string
+}
+
+func (a queryAuth) Apply(_ context.Context, req *http.Request) error {
+	q := req.URL.Query()
+	q.Set(a.name, a.value)
+	req.URL.RawQuery = q.Encode()
+	return nil
+}
+
+func (queryAuth) Invalidate() {}
+
+// clientCredentials implements the OAuth2 client-credentials grant with a
+// cached token that is refreshed shortly before it expires.
+type clientCredentials struct {
+	tokenURL, clientID, clientSecret string
+	scopes                           []string
+	audience                         string
+	client                           *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+const (
+	tokenRefreshSkew   = 30 * time.Second
+	defaultTokenExpiry = 5 * time.Minute
+)
+
+func (a *clientCredentials) Apply(ctx context.Context, req *http.Request) error {
+	tok, err := a.Token(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+tok)
+	return nil
+}
+
+func (a *clientCredentials) Invalidate() {
+	a.mu.Lock()
+	a.token = ""
+	a.mu.Unlock()
+}
+
+// Token returns the cached access token or fetches a new one.
+func (a *clientCredentials) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != "" && time.Now().Add(tokenRefreshSkew).Before(a.expires) {
+		return a.token, nil
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", a.clientID)
+	form.Set("client_secret", a.clientSecret)
+	if len(a.scopes) > 0 {
+		form.Set("scope", strings.Join(a.scopes, " "))
+	}
+	if a.audience != "" {
+		form.Set("audience", a.audience)
+	}
+
+	tr, err := requestToken(ctx, a.client, a.tokenURL, form)
+	if err != nil {
+		return "", err
+	}
+	a.token = tr.AccessToken
+	a.expires = tr.expiry()
+	return a.token, nil
+}
+
+// tokenResponse is the RFC 6749 token endpoint response.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+}
+
+func (t tokenResponse) expiry() time.Time {
+	if t.ExpiresIn <= 0 {
+		return time.Now().Add(defaultTokenExpiry)
+	}
+	return time.Now().Add(time.Duration(t.ExpiresIn) * time.Second)
+}
+
+func requestToken(ctx context.Context, client *http.Client, tokenURL string, form url.Values) (tokenResponse, error) {
+	var tr tokenResponse
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return tr, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return tr, fmt.Errorf("token request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return tr, fmt.Errorf("read token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return tr, fmt.Errorf("token endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return tr, fmt.Errorf("decode token response: %w", err)
+	}
+	if tr.AccessToken == "" {
+		return tr, fmt.Errorf("token response has no access_token")
+	}
+	return tr, nil
+}
+
+// NewAuthenticator builds the Authenticator described by cfg.
+func NewAuthenticator(cfg AuthConfig, secrets *SecretStore, client *http.Client) (Authenticator, error) {
+	secret := func() (string, error) {
+		if cfg.Secret == "" {
+			return "", fmt.Errorf("%s auth: secret not set", cfg.Type)
+		}
+		v, ok := secrets.Lookup(cfg.Secret)
+		if !ok {
+			return "", fmt.Errorf("%s auth: secret %q not found in environment or secrets file", cfg.Type, cfg.Secret)
+		}
+		return v, nil
+	}
+
+	switch strings.ToLower(cfg.Type) {
+	case "header":
+		if cfg.Name == "" {
+			return nil, fmt.Errorf("header auth: name not set")
+		}
+		v, err := secret()
+		if err != nil {
+			return nil, err
+		}
+		return headerAuth{cfg.Name, cfg.Prefix + v}, nil
+	case "basic":
+		v, err := secret()
+		if err != nil {
+			return nil, err
+		}
+		return basicAuth{cfg.Username, v}, nil
+	case "apikey":
+		if cfg.Name == "" {
+			return nil, fmt.Errorf("apiKey auth: name not set")
+		}
+		v, err := secret()
+		if err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(cfg.In, "query") {
+			return queryAuth{cfg.Name, cfg.Prefix + v}, nil
+		}
+		return headerAuth{cfg.Name, cfg.Prefix + v}, nil
+	case "oauth2":
+		if cfg.TokenURL == "" || cfg.ClientID == "" {
+			return nil, fmt.Errorf("oauth2 auth: tokenUrl and clientId are required")
+		}
+		v, err := secret()
+		if err != nil {
+			return nil, err
+		}
+		return &clientCredentials{
+			tokenURL:     cfg.TokenURL,
+			clientID:     cfg.ClientID,
+			clientSecret: v,
+			scopes:       cfg.Scopes,
+			audience:     cfg.Audience,
+			client:       client,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown auth type %q", cfg.Type)
+	}
+}
@@ -0,0 +1,114 @@
+package route
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientCredentialsCachesToken(t *testing.T) {
+	var calls int32
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		require.Equal(t, "docs", r.PostForm.Get("client_id"))
+		require.Equal(t, "s3cret", r.PostForm.Get("client_secret"))
+		atomic.AddInt32(&calls, 1)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "tok", "token_type": "Bearer", "expires_in": 3600,
+		})
+	}))
+	defer tokenSrv.Close()
+
+	t.Setenv("DOCS_CLIENT_SECRET", "s3cret")
+	secrets, err := LoadSecretStore("")
+	require.NoError(t, err)
+
+	a, err := NewAuthenticator(AuthConfig{
+		Type:     "oauth2",
+		TokenURL: tokenSrv.URL,
+		ClientID: "docs",
+		Secret:   "DOCS_CLIENT_SECRET",
+	}, secrets, tokenSrv.Client())
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "http://upstream.test/x", nil)
+		require.NoError(t, a.Apply(context.Background(), req))
+		require.Equal(t, "Bearer tok", req.Header.Get("Authorization"))
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	a.Invalidate()
+	req := httptest.NewRequest(http.MethodGet, "http://upstream.test/x", nil)
+	require.NoError(t, a.Apply(context.Background(), req))
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestAuthenticatorRequiresSecret(t *testing.T) {
+	secrets, err := LoadSecretStore("")
+	require.NoError(t, err)
+
+	_, err = NewAuthenticator(AuthConfig{Type: "header", Name: "X-Token", Secret: "MISSING_SECRET_FOR_TEST"}, secrets, nil)
+	require.Error(t, err)
+
+	t.Setenv("API_KEY_FOR_TEST", "k")
+	a, err := NewAuthenticator(AuthConfig{Type: "apiKey", In: "query", Name: "api_key", Secret: "API_KEY_FOR_TEST"}, secrets, nil)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "http://upstream.test/x?a=1", nil)
+	require.NoError(t, a.Apply(context.Background(), req))
+	require.Equal(t, "k", req.URL.Query().Get("api_key"))
+	require.Equal(t, "1", req.URL.Query().Get("a"))
+}
+
+func TestProxyChecksOriginForCredentials(t *testing.T) {
+	var calls atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	t.Setenv("ORIGIN_KEY_FOR_TEST", "k")
+	specs := []Spec{
+		{Name: "keyed", ProxyBase: backend.URL, Auth: &AuthConfig{Type: "header", Name: "X-Token", Secret: "ORIGIN_KEY_FOR_TEST"}},
+		{Name: "open", ProxyBase: backend.URL},
+	}
+	upstreams, err := buildUpstreams(specs)
+	require.NoError(t, err)
+	secrets, err := LoadSecretStore("")
+	require.NoError(t, err)
+	require.NoError(t, upstreams.LoadCredentials(secrets, nil))
+	guard, err := NewGuard(GuardConfig{}, nil)
+	require.NoError(t, err)
+	ps := NewProxyService(upstreams, nil, http.DefaultClient, guard, nil, nil, nil, nil)
+	ps.Origins = []string{"https://docs.example"}
+	h := WithCORS(ps.Origins, ps.ProxyHandler())
+
+	cases := []struct {
+		path, origin string
+		want         int
+	}{
+		{"/api/keyed/x", "", http.StatusOK},
+		{"/api/keyed/x", "https://docs.example", http.StatusOK},
+		{"/api/keyed/x", "http://example.com", http.StatusOK}, // same origin as the request host
+		{"/api/keyed/x", "https://evil.example", http.StatusForbidden},
+		{"/api/open/x", "https://evil.example", http.StatusOK},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, c.path, strings.NewReader("{}"))
+		if c.origin != "" {
+			req.Header.Set("Origin", c.origin)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		require.Equal(t, c.want, rec.Code, "%s from %q", c.path, c.origin)
+	}
+	require.EqualValues(t, 4, calls.Load())
+}
@@ -0,0 +1,116 @@
+package route
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"better-docs/indexing"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+)
+
+// Documents lazily loads and caches the parsed OpenAPI document of each spec.
+type Documents struct {
+	files map[string]string
+
+	mu   sync.Mutex
+	docs map[string]*openapi3.T
+}
+
+func NewDocuments(specs []Spec) *Documents {
+	files := make(map[string]string, len(specs))
+	for _, s := range specs {
+		files[strings.ToLower(s.Name)] = s.File
+	}
+	return &Documents{files: files, docs: make(map[string]*openapi3.T)}
+}
+
+func (d *Documents) Get(spec string) (*openapi3.T, error) {
+	spec = strings.ToLower(spec)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if doc, ok := d.docs[spec]; ok {
+		return doc, nil
+	}
+	file, ok := d.files[spec]
+	if !ok {
+		return nil, fmt.Errorf("unknown spec %q", spec)
+	}
+	doc, err := indexing.LoadSpecDocument(file)
+	if err != nil {
+		return nil, fmt.Errorf("load spec %s: %w", spec, err)
+	}
+	d.docs[spec] = doc
+	return doc, nil
+}
+
+// serverBasePaths returns the path component of every declared server.
+func serverBasePaths(doc *openapi3.T) []string {
+	var out []string
+	for _, sv := range doc.Servers {
+		if u, err := url.Parse(sv.URL); err == nil {
+			if p := strings.TrimRight(u.Path, "/"); p != "" {
+				out = append(out, p)
+			}
+		}
+	}
+	return out
+}
+
+// FindRoute resolves method and path to an operation of the spec. The path
+// may be relative to the spec or carry any of the servers' base paths or one
+// of the extra bases given. Literal segments win over templated ones.
+func (d *Documents) FindRoute(spec, method, path string, bases ...string) (*routers.Route, map[string]string, error) {
+	doc, err := d.Get(spec)
+	if err != nil {
+		return nil, nil, err
+	}
+	method = strings.ToUpper(method)
+
+	// stripped forms first: a base-prefixed path must not match a template
+	// that merely has enough segments
+	var candidates []string
+	for _, b := range append(bases, serverBasePaths(doc)...) {
+		b = strings.TrimRight(b, "/")
+		if b != "" && strings.HasPrefix(path, b+"/") {
+			candidates = append(candidates, strings.TrimPrefix(path, b))
+		}
+	}
+	candidates = append(candidates, path)
+
+	var (
+		best       *routers.Route
+		bestParams map[string]string
+	)
+	for _, rel := range candidates {
+		for tmpl, item := range doc.Paths.Map() {
+			op := item.GetOperation(method)
+			if op == nil {
+				continue
+			}
+			params, ok := indexing.MatchPath(tmpl, rel)
+			if !ok {
+				continue
+			}
+			if best == nil || len(params) < len(bestParams) {
+				best = &routers.Route{
+					Spec:      doc,
+					Path:      tmpl,
+					PathItem:  item,
+					Method:    method,
+					Operation: op,
+				}
+				if len(doc.Servers) > 0 {
+					best.Server = doc.Servers[0]
+				}
+				bestParams = params
+			}
+		}
+		if best != nil {
+			return best, bestParams, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("no operation found for %s %s in spec %q", method, path, spec)
+}
@@ -0,0 +1,287 @@
+package route
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	// envHeader and envQueryParam select the environment on /api/{spec}/...;
+	// both are stripped before the request goes upstream.
+	envHeader     = "X-Docs-Env"
+	envQueryParam = "_env"
+
+	defaultEnvName = "default"
+)
+
+// Environment is a named upstream profile (dev, staging, prod, …) of a spec.
+type Environment struct {
+	Name          string            `json:"name"`
+	ProxyBase     string            `json:"proxyBase"`
+	ProxyBases    []string          `json:"proxyBases,omitempty"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Auth          *AuthConfig       `json:"auth,omitempty"`
+	TLS           *TLSConfig        `json:"tls,omitempty"`
+	OutboundProxy *ProxyConfig      `json:"outboundProxy,omitempty"`
+}
+
+// Upstream is one resolved spec environment the proxy can forward to.
+type Upstream struct {
+	Spec    string
+	Env     string
+	Base    string
+	Headers map[string]string
+	Auth    Authenticator
+	Rules   *HeaderRules
+	// Pool holds Base and any further bases the proxy fails over to.
+	Pool *Pool
+
+	authConfig *AuthConfig
+}
+
+// Upstreams resolves a spec name and optional environment to an Upstream.
+type Upstreams struct {
+	envs     map[string]map[string]*Upstream
+	order    map[string][]string
+	defaults map[string]string
+}
+
+// buildUpstreams derives environments from specs. The top-level proxyBase,
+// headers and auth form the "default" environment and are inherited by named
+// environments that leave them unset. Header rules apply to every environment.
+func buildUpstreams(specs []Spec) (*Upstreams, error) {
+	u := &Upstreams{
+		envs:     make(map[string]map[string]*Upstream, len(specs)),
+		order:    make(map[string][]string, len(specs)),
+		defaults: make(map[string]string, len(specs)),
+	}
+	for _, s := range specs {
+		name := strings.ToLower(s.Name)
+		if err := s.HeaderRules.validate(); err != nil {
+			return nil, fmt.Errorf("spec %s: header rules: %w", s.Name, err)
+		}
+		envs := make(map[string]*Upstream)
+		add := func(env string, up *Upstream) error {
+			key := strings.ToLower(env)
+			if _, dup := envs[key]; dup {
+				return fmt.Errorf("spec %s: duplicate environment %q", s.Name, env)
+			}
+			envs[key] = up
+			u.order[name] = append(u.order[name], key)
+			return nil
+		}
+
+		pool := func(bases ...string) (*Pool, string, error) {
+			p, err := newPool(bases, s.HealthCheck)
+			if err != nil {
+				return nil, "", fmt.Errorf("spec %s: %w", s.Name, err)
+			}
+			if len(p.members) == 0 {
+				return nil, "", nil
+			}
+			return p, p.members[0].Base, nil
+		}
+
+		if s.ProxyBase != "" || len(s.ProxyBases) > 0 || len(s.Environments) == 0 {
+			p, base, err := pool(append([]string{s.ProxyBase}, s.ProxyBases...)...)
+			if err != nil {
+				return nil, err
+			}
+			if err := add(defaultEnvName, &Upstream{
+				Spec:       name,
+				Env:        defaultEnvName,
+				Base:       base,
+				Headers:    s.Headers,
+				Rules:      s.HeaderRules,
+				Pool:       p,
+				authConfig: s.Auth,
+			}); err != nil {
+				return nil, err
+			}
+		}
+		for _, e := range s.Environments {
+			if e.Name == "" {
+				return nil, fmt.Errorf("spec %s: environment without name", s.Name)
+			}
+			headers := make(map[string]string, len(s.Headers)+len(e.Headers))
+			for k, v := range s.Headers {
+				headers[k] = v
+			}
+			for k, v := range e.Headers {
+				headers[k] = v
+			}
+			auth := e.Auth
+			if auth == nil {
+				auth = s.Auth
+			}
+			bases := append([]string{e.ProxyBase}, e.ProxyBases...)
+			if e.ProxyBase == "" && len(e.ProxyBases) == 0 {
+				bases = append([]string{s.ProxyBase}, s.ProxyBases...)
+			}
+			p, base, err := pool(bases...)
+			if err != nil {
+				return nil, err
+			}
+			if err := add(e.Name, &Upstream{
+				Spec:       name,
+				Env:        strings.ToLower(e.Name),
+				Base:       base,
+				Headers:    headers,
+				Rules:      s.HeaderRules,
+				Pool:       p,
+				authConfig: auth,
+			}); err != nil {
+				return nil, err
+			}
+		}
+
+		def := strings.ToLower(s.DefaultEnvironment)
+		if def == "" {
+			def = u.order[name][0]
+		}
+		if _, ok := envs[def]; !ok {
+			return nil, fmt.Errorf("spec %s: default environment %q not defined", s.Name, s.DefaultEnvironment)
+		}
+		u.envs[name] = envs
+		u.defaults[name] = def
+	}
+	return u, nil
+}
+
+// LoadCredentials builds the Authenticator of every environment that has auth.
+func (u *Upstreams) LoadCredentials(secrets *SecretStore, client *http.Client) error {
+	for _, envs := range u.envs {
+		for _, up := range envs {
+			if up.authConfig == nil {
+				continue
+			}
+			a, err := NewAuthenticator(*up.authConfig, secrets, client)
+			if err != nil {
+				return fmt.Errorf("spec %s env %s: %w", up.Spec, up.Env, err)
+			}
+			up.Auth = a
+		}
+	}
+	return nil
+}
+
+// Resolve returns the spec's named environment, or its default when env is empty.
+func (u *Upstreams) Resolve(spec, env string) (*Upstream, error) {
+	spec = strings.ToLower(spec)
+	envs, ok := u.envs[spec]
+	if !ok {
+		return nil, fmt.Errorf("unknown spec %q", spec)
+	}
+	if env == "" {
+		env = u.defaults[spec]
+	}
+	up, ok := envs[strings.ToLower(env)]
+	if !ok {
+		return nil, fmt.Errorf("spec %s has no environment %q", spec, env)
+	}
+	return up, nil
+}
+
+// Environments lists a spec's environments in declaration order.
+func (u *Upstreams) Environments(spec string) []*Upstream {
+	spec = strings.ToLower(spec)
+	out := make([]*Upstream, 0, len(u.order[spec]))
+	for _, env := range u.order[spec] {
+		out = append(out, u.envs[spec][env])
+	}
+	return out
+}
+
+// Default returns the spec's default environment name.
+func (u *Upstreams) Default(spec string) string {
+	return u.defaults[strings.ToLower(spec)]
+}
+
+// ForHost returns the environment with a pool member on the target's host.
+func (u *Upstreams) ForHost(target *url.URL) *Upstream {
+	for _, up := range u.All() {
+		if up.Pool == nil {
+			continue
+		}
+		for _, base := range up.Pool.Bases() {
+			if b, err := url.Parse(base); err == nil && b.Host != "" && strings.EqualFold(b.Host, target.Host) {
+				return up
+			}
+		}
+	}
+	return nil
+}
+
+// All returns every upstream, sorted by spec and environment.
+func (u *Upstreams) All() []*Upstream {
+	var out []*Upstream
+	for _, envs := range u.envs {
+		for _, up := range envs {
+			out = append(out, up)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Spec != out[j].Spec {
+			return out[i].Spec < out[j].Spec
+		}
+		return out[i].Env < out[j].Env
+	})
+	return out
+}
+
+// selectedEnv reads the environment selector and removes it from the request.
+func selectedEnv(r *http.Request) string {
+	env := r.Header.Get(envHeader)
+	r.Header.Del(envHeader)
+	q := r.URL.Query()
+	if v := q.Get(envQueryParam); v != "" {
+		env = v
+	}
+	if _, ok := q[envQueryParam]; ok {
+		q.Del(envQueryParam)
+		r.URL.RawQuery = q.Encode()
+	}
+	return env
+}
+
+// EnvironmentsHandler lists a spec's environments.
+//
+// GET /environments/{spec}
+func EnvironmentsHandler(upstreams *Upstreams) http.HandlerFunc {
+	type environment struct {
+		Name      string   `json:"name"`
+		ProxyBase string   `json:"proxyBase"`
+		Headers   []string `json:"headers,omitempty"`
+		HasAuth   bool     `json:"hasAuth"`
+		Default   bool     `json:"default"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		spec := strings.Trim(strings.TrimPrefix(r.URL.Path, "/environments/"), "/")
+		envs := upstreams.Environments(spec)
+		if len(envs) == 0 {
+			http.Error(w, "spec not found", http.StatusNotFound)
+			return
+		}
+		def := upstreams.Default(spec)
+
+		out := make([]environment, 0, len(envs))
+		for _, up := range envs {
+			names := make([]string, 0, len(up.Headers))
+			for k := range up.Headers {
+				names = append(names, k)
+			}
+			sort.Strings(names)
+			out = append(out, environment{up.Env, up.Base, names, up.Auth != nil, up.Env == def})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(out); err != nil {
+			http.Error(w, "failed to write response: "+err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
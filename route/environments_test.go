@@ -0,0 +1,69 @@
+package route
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxySelectsEnvironment(t *testing.T) {
+	seen := make(chan *http.Request, 1)
+	backend := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen <- r
+			_, _ = io.WriteString(w, name)
+		}))
+	}
+	dev, staging := backend("dev"), backend("staging")
+	defer dev.Close()
+	defer staging.Close()
+
+	upstreams, err := buildUpstreams([]Spec{{
+		Name:    "orders",
+		Headers: map[string]string{"X-Tenant": "acme"},
+		Environments: []Environment{
+			{Name: "dev", ProxyBase: dev.URL + "/v1"},
+			{Name: "staging", ProxyBase: staging.URL + "/v1", Headers: map[string]string{"X-Tenant": "beta"}},
+		},
+		DefaultEnvironment: "dev",
+	}})
+	require.NoError(t, err)
+
+	guard, err := NewGuard(GuardConfig{}, nil)
+	require.NoError(t, err)
+	h := NewProxyService(upstreams, nil, http.DefaultClient, guard, nil, nil, nil, nil).ProxyHandler()
+
+	call := func(req *http.Request) (string, *http.Request) {
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		return rec.Body.String(), <-seen
+	}
+
+	body, up := call(httptest.NewRequest(http.MethodGet, "/api/orders/items?x=1", nil))
+	require.Equal(t, "dev", body)
+	require.Equal(t, "/v1/items", up.URL.Path)
+	require.Equal(t, "acme", up.Header.Get("X-Tenant"))
+
+	body, up = call(httptest.NewRequest(http.MethodGet, "/api/orders/items?x=1&_env=staging", nil))
+	require.Equal(t, "staging", body)
+	require.Equal(t, "x=1", up.URL.RawQuery)
+	require.Equal(t, "beta", up.Header.Get("X-Tenant"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/orders/items", nil)
+	req.Header.Set(envHeader, "staging")
+	body, up = call(req)
+	require.Equal(t, "staging", body)
+	require.Empty(t, up.Header.Get(envHeader))
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/api/orders/items?_env=prod", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	envs := upstreams.Environments("orders")
+	require.Len(t, envs, 2)
+	require.Equal(t, "dev", upstreams.Default("orders"))
+}
@@ -0,0 +1,81 @@
+package route
+
+import (
+	"io"
+	"net/http"
+
+	"better-docs/indexing"
+	"better-docs/parser"
+)
+
+// ExportHandler converts a logged request, in any format /raSearch accepts,
+// into a curl or HTTPie command, a HAR log, a Postman collection item or a
+// RestAssured snippet. When the request matches an operation its path params
+// are resolved first, so exports can write the path as a template.
+//
+// POST /export?format=curl|httpie|har|postman|restassured
+func (s *SearchService) ExportHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		format := r.URL.Query().Get("format")
+		switch format {
+		case "curl", "httpie", "har", "postman", "restassured":
+		default:
+			http.Error(w, "format must be curl, httpie, har, postman or restassured", http.StatusBadRequest)
+			return
+		}
+
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "failed to read body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		in, err := detectFormat(r, string(data), s.LogPrefix)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		pr, pres, err := in.Parse(string(data))
+		if err != nil {
+			http.Error(w, "parse error: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// an unmatched request still exports, just without a path template
+		opID := ""
+		if specName, op, pathParams, err := indexing.FindOperation(s.Index, s.Registry, pr.Method, pr.URI); err == nil {
+			opID = op
+			pr.PathParams = pathParams
+			pr.URI = indexing.ResolveURL(s.Registry, specName, pr.URI)
+		}
+
+		var out []byte
+		contentType := "text/plain; charset=utf-8"
+		switch format {
+		case "curl":
+			out = []byte(parser.ToCurl(pr))
+		case "httpie":
+			out = []byte(parser.ToHTTPie(pr))
+		case "har":
+			out, err = parser.ToHAR(pr, pres)
+			contentType = "application/json"
+		case "postman":
+			out, err = parser.ToPostmanItem(pr, opID)
+			contentType = "application/json"
+		case "restassured":
+			var snippet string
+			snippet, err = parser.ToRestAssured(pr, pres)
+			out = []byte(snippet)
+		}
+		if err != nil {
+			http.Error(w, "export error: "+err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Write(out)
+	}
+}
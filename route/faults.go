@@ -0,0 +1,245 @@
+package route
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// faultSessionHeader scopes fault rules to the tester's own traffic.
+	faultSessionHeader = "X-Docs-Fault-Session"
+	faultHeader        = "X-Docs-Fault"
+)
+
+// FaultRule injects a failure into proxied calls carrying a matching
+// session header. Spec, Operation (an operationId) and Method narrow the
+// calls it applies to; an empty field matches everything.
+type FaultRule struct {
+	ID        string `json:"id"`
+	Session   string `json:"session"`
+	Spec      string `json:"spec,omitempty"`
+	Operation string `json:"operation,omitempty"`
+	Method    string `json:"method,omitempty"`
+
+	// Probability is the chance the rule fires, in [0, 1]; when omitted the
+	// rule always fires, and 0 turns it off.
+	Probability *float64 `json:"probability,omitempty"`
+
+	Latency string `json:"latency,omitempty"` // e.g. "750ms"
+	Status  int    `json:"status,omitempty"`  // answer with this status instead of calling upstream
+	Body    string `json:"body,omitempty"`    // body sent with Status
+	Drop    bool   `json:"drop,omitempty"`    // close the client connection without a response
+	Corrupt bool   `json:"corrupt,omitempty"` // mangle the upstream response body
+
+	latency time.Duration
+}
+
+func (f *FaultRule) validate() error {
+	if f.Session == "" {
+		return fmt.Errorf("session is required")
+	}
+	if f.Probability != nil && (*f.Probability < 0 || *f.Probability > 1) {
+		return fmt.Errorf("probability must be between 0 and 1")
+	}
+	if f.Latency != "" {
+		d, err := time.ParseDuration(f.Latency)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid latency %q", f.Latency)
+		}
+		f.latency = d
+	}
+	if f.Status != 0 && (f.Status < 100 || f.Status > 599) {
+		return fmt.Errorf("invalid status %d", f.Status)
+	}
+	if f.latency == 0 && f.Status == 0 && !f.Drop && !f.Corrupt {
+		return fmt.Errorf("rule has no effect: set latency, status, drop or corrupt")
+	}
+	return nil
+}
+
+// faultPlan is the combined effect of the rules that fired for one call.
+type faultPlan struct {
+	ids     []string
+	latency time.Duration
+	status  int
+	body    string
+	drop    bool
+	corrupt bool
+}
+
+// FaultInjector holds the fault rules managed through /faults.
+type FaultInjector struct {
+	Docs *Documents
+
+	mu    sync.Mutex
+	seq   int
+	rules []*FaultRule
+	rnd   *rand.Rand
+}
+
+func NewFaultInjector(docs *Documents) *FaultInjector {
+	return &FaultInjector{Docs: docs, rnd: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// plan returns the faults to inject into req, or nil. The session header is
+// removed so it never reaches the upstream.
+func (f *FaultInjector) plan(r, req *http.Request, up *Upstream) *faultPlan {
+	session := r.Header.Get(faultSessionHeader)
+	req.Header.Del(faultSessionHeader)
+	if f == nil || session == "" || up == nil {
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var opID *string
+	operation := func() string {
+		if opID == nil {
+			var id string
+			var bases []string
+			if b, err := url.Parse(up.Base); err == nil {
+				bases = append(bases, b.Path)
+			}
+			if route, _, err := f.Docs.FindRoute(up.Spec, req.Method, req.URL.Path, bases...); err == nil {
+				id = route.Operation.OperationID
+			}
+			opID = &id
+		}
+		return *opID
+	}
+
+	var p *faultPlan
+	for _, rule := range f.rules {
+		if rule.Session != session ||
+			(rule.Spec != "" && !strings.EqualFold(rule.Spec, up.Spec)) ||
+			(rule.Method != "" && !strings.EqualFold(rule.Method, req.Method)) ||
+			(rule.Operation != "" && rule.Operation != operation()) {
+			continue
+		}
+		if rule.Probability != nil && f.rnd.Float64() >= *rule.Probability {
+			continue
+		}
+		if p == nil {
+			p = &faultPlan{}
+		}
+		p.ids = append(p.ids, rule.ID)
+		p.latency += rule.latency
+		if p.status == 0 && rule.Status != 0 {
+			p.status, p.body = rule.Status, rule.Body
+		}
+		p.drop = p.drop || rule.Drop
+		p.corrupt = p.corrupt || rule.Corrupt
+	}
+	return p
+}
+
+// delay sleeps for the planned latency, returning early if the client goes away.
+func (p *faultPlan) delay(ctx context.Context) error {
+	if p.latency == 0 {
+		return nil
+	}
+	t := time.NewTimer(p.latency)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// corruptBody truncates the body at a random point and flips a byte, which
+// breaks most structured payloads while keeping the status and headers.
+func (f *FaultInjector) corruptBody(body []byte) []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(body) == 0 {
+		return []byte{0xff}
+	}
+	out := append([]byte(nil), body[:f.rnd.Intn(len(body))+1]...)
+	i := f.rnd.Intn(len(out))
+	out[i] ^= 0xff
+	return out
+}
+
+func (f *FaultInjector) add(rule *FaultRule) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq++
+	rule.ID = strconv.Itoa(f.seq)
+	f.rules = append(f.rules, rule)
+}
+
+// remove deletes the rule with the given id, or every rule of session.
+func (f *FaultInjector) remove(id, session string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	kept := f.rules[:0]
+	for _, rule := range f.rules {
+		if (id != "" && rule.ID == id) || (id == "" && rule.Session == session) {
+			continue
+		}
+		kept = append(kept, rule)
+	}
+	n := len(f.rules) - len(kept)
+	f.rules = kept
+	return n
+}
+
+// FaultsHandler manages fault rules.
+//
+// GET /faults[?session=...] lists rules.
+// POST /faults adds a rule and returns it with its id.
+// DELETE /faults/{id} removes one rule; DELETE /faults?session=... removes a session's rules.
+func (f *FaultInjector) FaultsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/faults"), "/")
+		session := r.URL.Query().Get("session")
+
+		switch r.Method {
+		case http.MethodGet:
+			f.mu.Lock()
+			list := make([]*FaultRule, 0, len(f.rules))
+			for _, rule := range f.rules {
+				if session == "" || rule.Session == session {
+					list = append(list, rule)
+				}
+			}
+			f.mu.Unlock()
+			writeJSON(w, http.StatusOK, list)
+		case http.MethodPost:
+			var rule FaultRule
+			if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+				http.Error(w, "invalid rule: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := rule.validate(); err != nil {
+				http.Error(w, "invalid rule: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			f.add(&rule)
+			writeJSON(w, http.StatusCreated, &rule)
+		case http.MethodDelete:
+			if id == "" && session == "" {
+				http.Error(w, "rule id or session required", http.StatusBadRequest)
+				return
+			}
+			if f.remove(id, session) == 0 && id != "" {
+				http.Error(w, "rule not found", http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
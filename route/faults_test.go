@@ -0,0 +1,81 @@
+package route
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyFaultInjection(t *testing.T) {
+	calls := 0
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		require.Empty(t, r.Header.Get(faultSessionHeader))
+		_, _ = io.WriteString(w, `{"id":1,"name":"rex"}`)
+	}))
+	defer backend.Close()
+
+	specs := []Spec{{Name: "pets", ProxyBase: backend.URL}}
+	upstreams, err := buildUpstreams(specs)
+	require.NoError(t, err)
+	guard, err := NewGuard(GuardConfig{}, nil)
+	require.NoError(t, err)
+	faults := NewFaultInjector(NewDocuments(specs))
+	h := NewProxyService(upstreams, nil, http.DefaultClient, guard, nil, nil, faults, nil).ProxyHandler()
+	admin := faults.FaultsHandler()
+
+	addRule := func(body string) int {
+		rec := httptest.NewRecorder()
+		admin(rec, httptest.NewRequest(http.MethodPost, "/faults", strings.NewReader(body)))
+		return rec.Code
+	}
+	call := func(session string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/pets/pets/1", nil)
+		if session != "" {
+			req.Header.Set(faultSessionHeader, session)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusBadRequest, addRule(`{"spec":"pets","status":503}`))
+	require.Equal(t, http.StatusBadRequest, addRule(`{"session":"alice","probability":1.5}`))
+
+	require.Equal(t, http.StatusCreated, addRule(`{"session":"alice","spec":"pets","status":503,"body":"down"}`))
+
+	// other testers are unaffected
+	rec := call("bob")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, 1, calls)
+
+	rec = call("alice")
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.Equal(t, "1", rec.Header().Get(faultHeader))
+	require.Equal(t, 1, calls)
+
+	del := httptest.NewRecorder()
+	admin(del, httptest.NewRequest(http.MethodDelete, "/faults?session=alice", nil))
+	require.Equal(t, http.StatusNoContent, del.Code)
+
+	require.Equal(t, http.StatusCreated, addRule(`{"session":"alice","latency":"50ms","corrupt":true}`))
+	start := time.Now()
+	rec = call("alice")
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotEqual(t, `{"id":1,"name":"rex"}`, rec.Body.String())
+
+	// probability 0 switches a rule off
+	require.Equal(t, http.StatusCreated, addRule(`{"session":"dave","status":500,"probability":0}`))
+	rec = call("dave")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Empty(t, rec.Header().Get(faultHeader))
+
+	require.Equal(t, http.StatusCreated, addRule(`{"session":"carol","drop":true}`))
+	require.PanicsWithValue(t, http.ErrAbortHandler, func() { call("carol") })
+}
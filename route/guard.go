@@ -0,0 +1,224 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"better-docs/indexing"
+)
+
+var (
+	errHostNotAllowed = errors.New("host is not in the proxy allowlist")
+	errBlockedAddress = errors.New("address is in a blocked range")
+
+	// blockedNets are refused unless explicitly allowed: loopback, private,
+	// link-local (which covers cloud metadata), CGNAT and unique-local ranges.
+	blockedNets = mustParseCIDRs(
+		"0.0.0.0/8",
+		"10.0.0.0/8",
+		"100.64.0.0/10",
+		"127.0.0.0/8",
+		"169.254.0.0/16",
+		"172.16.0.0/12",
+		"192.168.0.0/16",
+		"::/128",
+		"::1/128",
+		"fc00::/7",
+		"fe80::/10",
+	)
+)
+
+// GuardConfig holds the operator-supplied allowlist settings.
+type GuardConfig struct {
+	// AllowHosts are extra entries: "host", "host:port", "*.suffix" or a CIDR.
+	AllowHosts []string
+	// AllowPrivate disables the private/link-local/metadata range check.
+	AllowPrivate bool
+}
+
+// Guard decides which upstream URLs the proxy may reach.
+//
+// Hosts listed exactly (derived from specs or configured) are trusted and may
+// resolve to internal addresses. Hosts admitted by a wildcard are still
+// subject to the blocked-range check at dial time.
+type Guard struct {
+	trusted      map[string]struct{}
+	wildcards    []string
+	nets         []*net.IPNet
+	allowPrivate bool
+}
+
+func NewGuard(cfg GuardConfig, derived []string) (*Guard, error) {
+	g := &Guard{
+		trusted:      make(map[string]struct{}),
+		allowPrivate: cfg.AllowPrivate,
+	}
+	for _, h := range derived {
+		g.addHost(h)
+	}
+	for _, entry := range cfg.AllowHosts {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+		case strings.Contains(entry, "/"):
+			_, n, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid allowlist CIDR %q: %w", entry, err)
+			}
+			g.nets = append(g.nets, n)
+		case strings.HasPrefix(entry, "*."):
+			g.wildcards = append(g.wildcards, strings.ToLower(entry[1:]))
+		case entry == "*":
+			g.wildcards = append(g.wildcards, "")
+		default:
+			g.addHost(entry)
+		}
+	}
+	return g, nil
+}
+
+// addHost trusts h. A bare host allows every port; host:port only that one.
+func (g *Guard) addHost(h string) {
+	h = strings.ToLower(strings.TrimSpace(h))
+	if h == "" {
+		return
+	}
+	g.trusted[h] = struct{}{}
+}
+
+// AllowedHosts derives the default allowlist from every registered spec's
+// servers, every proxyBase and every outbound jump proxy.
+func AllowedHosts(specs []Spec, reg indexing.Registry) []string {
+	hosts := SpecHosts(specs, reg)
+	out := make([]string, 0, len(hosts))
+	for h := range hosts {
+		out = append(out, h)
+	}
+	return append(out, proxyHosts(specs)...)
+}
+
+func (g *Guard) isTrusted(host string) bool {
+	_, ok := g.trusted[strings.ToLower(host)]
+	return ok
+}
+
+func (g *Guard) inAllowedNet(ip net.IP) bool {
+	for _, n := range g.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Guard) matchesWildcard(host string) bool {
+	host = strings.ToLower(host)
+	for _, w := range g.wildcards {
+		if w == "" || strings.HasSuffix(host, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckURL rejects targets whose scheme or host is not allowed.
+func (g *Guard) CheckURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	host := u.Hostname()
+	if host == "" {
+		return errors.New("missing host")
+	}
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+	if g.isTrusted(net.JoinHostPort(host, port)) || g.isTrusted(host) || g.matchesWildcard(host) {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && g.inAllowedNet(ip) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", errHostNotAllowed, u.Host)
+}
+
+// checkIP applies the blocked-range check to one resolved address.
+func (g *Guard) checkIP(ip net.IP) error {
+	if g.allowPrivate || g.inAllowedNet(ip) {
+		return nil
+	}
+	for _, n := range blockedNets {
+		if n.Contains(ip) {
+			return fmt.Errorf("%w: %s", errBlockedAddress, ip)
+		}
+	}
+	return nil
+}
+
+// DialContext resolves the host itself so the blocked-range check applies to
+// the address actually dialed, defeating DNS rebinding.
+func (g *Guard) DialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if g.isTrusted(addr) || g.isTrusted(host) {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+		if err != nil {
+			return nil, err
+		}
+		var lastErr error
+		for _, ip := range ips {
+			if err := g.checkIP(ip); err != nil {
+				lastErr = err
+				continue
+			}
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		if lastErr == nil {
+			lastErr = fmt.Errorf("no addresses for %s", host)
+		}
+		return nil, lastErr
+	}
+}
+
+// CheckRedirect applies CheckURL to every redirect hop.
+func (g *Guard) CheckRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	return g.CheckURL(req.URL)
+}
+
+// Transport returns a clone of the default transport dialing through the guard.
+func (g *Guard) Transport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = g.DialContext(&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second})
+	return t
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	out := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			panic(err)
+		}
+		out = append(out, n)
+	}
+	return out
+}
@@ -0,0 +1,65 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuardCheckURL(t *testing.T) {
+	g, err := NewGuard(GuardConfig{
+		AllowHosts: []string{"extra.test", "*.corp.test", "203.0.113.0/24", "secure.test:443"},
+	}, []string{"api.example.com", "svc.internal:8443"})
+	require.NoError(t, err)
+
+	allowed := []string{
+		"http://api.example.com/v1/items",
+		"https://svc.internal:8443/x",
+		"https://extra.test/",
+		"http://orders.corp.test/",
+		"http://203.0.113.7/",
+		"http://api.example.com:8080/v1",
+		"https://secure.test/",
+	}
+	for _, raw := range allowed {
+		u, _ := url.Parse(raw)
+		require.NoError(t, g.CheckURL(u), raw)
+	}
+
+	denied := []string{
+		"http://169.254.169.254/latest/meta-data/",
+		"http://evil.test/",
+		"http://corp.test.evil.test/",
+		"file:///etc/passwd",
+		// a port-specific entry does not allow the host's other ports
+		"https://svc.internal/x",
+		"http://svc.internal:22/",
+		"http://secure.test/",
+	}
+	for _, raw := range denied {
+		u, _ := url.Parse(raw)
+		require.Error(t, g.CheckURL(u), raw)
+	}
+}
+
+func TestGuardDialBlocksPrivateRanges(t *testing.T) {
+	g, err := NewGuard(GuardConfig{AllowHosts: []string{"*"}}, []string{"127.0.0.1"})
+	require.NoError(t, err)
+	dial := g.DialContext(&net.Dialer{})
+
+	_, err = dial(context.Background(), "tcp", "localhost:1")
+	require.True(t, errors.Is(err, errBlockedAddress), "got %v", err)
+
+	for _, ip := range []string{"10.1.2.3", "169.254.169.254", "::1", "fd00::1"} {
+		require.Error(t, g.checkIP(net.ParseIP(ip)), ip)
+	}
+	require.NoError(t, g.checkIP(net.ParseIP("93.184.216.34")))
+
+	open, err := NewGuard(GuardConfig{AllowPrivate: true}, nil)
+	require.NoError(t, err)
+	require.NoError(t, open.checkIP(net.ParseIP("10.1.2.3")))
+}
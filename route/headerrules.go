@@ -0,0 +1,137 @@
+package route
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// HeaderRule is one declarative header transformation.
+//
+//	{"action": "set", "name": "X-Tenant", "value": "acme"}
+//	{"action": "add", "name": "Via", "value": "better-docs"}
+//	{"action": "remove", "name": "Cookie"}             // a trailing * matches a prefix
+//	{"action": "rename", "name": "X-User", "to": "X-Forwarded-User"}
+//	{"action": "proxyLocation"}                        // response only; name defaults to Location
+type HeaderRule struct {
+	Action string `json:"action"`
+	Name   string `json:"name,omitempty"`
+	Value  string `json:"value,omitempty"`
+	To     string `json:"to,omitempty"`
+}
+
+// HeaderRules transform the headers of proxied requests before they go
+// upstream and of responses before they reach the client.
+type HeaderRules struct {
+	Request  []HeaderRule `json:"request,omitempty"`
+	Response []HeaderRule `json:"response,omitempty"`
+}
+
+func (hr *HeaderRules) validate() error {
+	if hr == nil {
+		return nil
+	}
+	check := func(side string, rules []HeaderRule) error {
+		for i, r := range rules {
+			switch strings.ToLower(r.Action) {
+			case "set", "add", "remove":
+				if r.Name == "" {
+					return fmt.Errorf("%s rule %d: %s needs a name", side, i, r.Action)
+				}
+			case "rename":
+				if r.Name == "" || r.To == "" {
+					return fmt.Errorf("%s rule %d: rename needs name and to", side, i)
+				}
+			case "proxylocation":
+				if side != "response" {
+					return fmt.Errorf("%s rule %d: proxyLocation only applies to responses", side, i)
+				}
+			default:
+				return fmt.Errorf("%s rule %d: unknown action %q", side, i, r.Action)
+			}
+		}
+		return nil
+	}
+	if err := check("request", hr.Request); err != nil {
+		return err
+	}
+	return check("response", hr.Response)
+}
+
+// headerMatches reports whether key matches name, which may end in * to
+// match a prefix.
+func headerMatches(name, key string) bool {
+	if p, ok := strings.CutSuffix(name, "*"); ok {
+		return strings.HasPrefix(strings.ToLower(key), strings.ToLower(p))
+	}
+	return strings.EqualFold(name, key)
+}
+
+func applyHeaderRules(rules []HeaderRule, h http.Header, up *Upstream) {
+	for _, r := range rules {
+		switch strings.ToLower(r.Action) {
+		case "set":
+			h.Set(r.Name, r.Value)
+		case "add":
+			h.Add(r.Name, r.Value)
+		case "remove":
+			for k := range h {
+				if headerMatches(r.Name, k) {
+					delete(h, k)
+				}
+			}
+		case "rename":
+			if v := h.Values(r.Name); len(v) > 0 {
+				h.Del(r.Name)
+				for _, vv := range v {
+					h.Add(r.To, vv)
+				}
+			}
+		case "proxylocation":
+			name := r.Name
+			if name == "" {
+				name = "Location"
+			}
+			if v := h.Get(name); v != "" {
+				h.Set(name, proxyLocation(v, up))
+			}
+		}
+	}
+}
+
+// proxyLocation rewrites a location on the upstream back through
+// /api/{spec}/ so redirects and created-resource links stay on the proxy.
+// Locations elsewhere are returned unchanged.
+func proxyLocation(loc string, up *Upstream) string {
+	base, err := url.Parse(up.Base)
+	if err != nil {
+		return loc
+	}
+	u, err := url.Parse(loc)
+	if err != nil || (u.Host != "" && !strings.EqualFold(u.Host, base.Host)) {
+		return loc
+	}
+	if u.Host == "" && !strings.HasPrefix(u.Path, "/") {
+		return loc
+	}
+	rel := u.Path
+	if bp := strings.TrimRight(base.Path, "/"); bp != "" {
+		var ok bool
+		if rel, ok = strings.CutPrefix(u.Path, bp); !ok || (rel != "" && !strings.HasPrefix(rel, "/")) {
+			return loc
+		}
+	}
+
+	out := &url.URL{Path: path.Join("/api", up.Spec, rel), RawQuery: u.RawQuery, Fragment: u.Fragment}
+	if strings.HasSuffix(rel, "/") {
+		out.Path += "/"
+	}
+	if up.Env != defaultEnvName {
+		q := out.Query()
+		q.Set(envQueryParam, up.Env)
+		out.RawQuery = q.Encode()
+	}
+	return out.String()
+}
@@ -0,0 +1,72 @@
+package route
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyHeaderRules(t *testing.T) {
+	seen := make(chan http.Header, 1)
+	var backendURL string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen <- r.Header.Clone()
+		w.Header().Set("Location", backendURL+"/v1/pets/7?view=full")
+		w.Header().Set("Set-Cookie", "upstream=1")
+		w.Header().Set("X-Internal-Trace", "abc")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, "{}")
+	}))
+	defer backend.Close()
+	backendURL = backend.URL
+
+	upstreams, err := buildUpstreams([]Spec{{
+		Name:      "pets",
+		ProxyBase: backend.URL + "/v1",
+		HeaderRules: &HeaderRules{
+			Request: []HeaderRule{
+				{Action: "remove", Name: "Cookie"},
+				{Action: "set", Name: "X-Tenant", Value: "acme"},
+				{Action: "rename", Name: "X-User", To: "X-Forwarded-User"},
+			},
+			Response: []HeaderRule{
+				{Action: "remove", Name: "Set-Cookie"},
+				{Action: "remove", Name: "X-Internal-*"},
+				{Action: "proxyLocation"},
+			},
+		},
+		Environments: []Environment{{Name: "staging", ProxyBase: backend.URL + "/v1"}},
+	}})
+	require.NoError(t, err)
+	guard, err := NewGuard(GuardConfig{}, nil)
+	require.NoError(t, err)
+	h := NewProxyService(upstreams, nil, http.DefaultClient, guard, nil, nil, nil, nil).ProxyHandler()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/pets/pets", nil)
+	req.Header.Set("Cookie", "theirs=1")
+	req.Header.Set("X-User", "ann")
+	rec := httptest.NewRecorder()
+	h(rec, req)
+
+	up := <-seen
+	require.Empty(t, up.Get("Cookie"))
+	require.Equal(t, "acme", up.Get("X-Tenant"))
+	require.Empty(t, up.Get("X-User"))
+	require.Equal(t, "ann", up.Get("X-Forwarded-User"))
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Empty(t, rec.Header().Get("Set-Cookie"))
+	require.Empty(t, rec.Header().Get("X-Internal-Trace"))
+	require.Equal(t, "/api/pets/pets/7?view=full", rec.Header().Get("Location"))
+
+	staging, err := upstreams.Resolve("pets", "staging")
+	require.NoError(t, err)
+	require.Equal(t, "/api/pets/pets/7?_env=staging", proxyLocation(backend.URL+"/v1/pets/7", staging))
+	require.Equal(t, "https://elsewhere.test/x", proxyLocation("https://elsewhere.test/x", staging))
+
+	_, err = buildUpstreams([]Spec{{Name: "bad", HeaderRules: &HeaderRules{Request: []HeaderRule{{Action: "proxyLocation"}}}}})
+	require.Error(t, err)
+}
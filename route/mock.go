@@ -0,0 +1,474 @@
+package route
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+)
+
+const maxMockDepth = 8
+
+type MockService struct {
+	Docs *Documents
+	// Store backs the stateful CRUD mode; nil keeps every response stateless.
+	Store *MockStore
+}
+
+func NewMockService(docs *Documents, stateful bool) *MockService {
+	s := &MockService{Docs: docs}
+	if stateful {
+		s.Store = NewMockStore()
+	}
+	return s
+}
+
+// preferences holds the RFC 7240 Prefer directives the mock understands:
+// code=404, example=name, seed=42.
+type preferences struct {
+	code    string
+	example string
+	seed    *int64
+}
+
+func parsePrefer(h []string) preferences {
+	var p preferences
+	for _, line := range h {
+		for _, part := range strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ';' }) {
+			k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+			v = strings.Trim(strings.TrimSpace(v), `"`)
+			switch strings.ToLower(strings.TrimSpace(k)) {
+			case "code":
+				p.code = v
+			case "example":
+				p.example = v
+			case "seed":
+				if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+					p.seed = &n
+				}
+			}
+		}
+	}
+	return p
+}
+
+// MockHandler answers /mock/{spec}/{path...} from the spec: a declared
+// example when there is one, otherwise a payload synthesized from the
+// response schema. Generation is seeded from method and path unless the
+// caller sends Prefer: seed=N, so repeated calls return the same payload.
+// In stateful mode collection and item operations are served from the store
+// instead; Prefer: code=N still forces a canned response.
+func (s *MockService) MockHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/mock/"), "/", 2)
+		if len(parts) < 2 {
+			http.Error(w, "invalid mock path", http.StatusBadRequest)
+			return
+		}
+		route, params, err := s.Docs.FindRoute(parts[0], r.Method, "/"+parts[1])
+		if err != nil {
+			http.Error(w, "no match: "+err.Error(), http.StatusNotFound)
+			return
+		}
+
+		prefs := parsePrefer(r.Header.Values("Prefer"))
+		if s.Store != nil && prefs.code == "" && s.serveStateful(w, r, route, params) {
+			return
+		}
+		status, resp, err := selectResponse(route.Operation, prefs.code)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		seed := mockSeed(r.Method, r.URL.Path)
+		if prefs.seed != nil {
+			seed = *prefs.seed
+		}
+		writeMockResponse(w, r, route, params, status, resp, prefs, seed)
+	}
+}
+
+func mockSeed(method, path string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(method + " " + path))
+	return int64(h.Sum64() & 0x7fffffffffffffff)
+}
+
+// selectResponse picks the requested status, else the lowest 2xx, else default.
+func selectResponse(op *openapi3.Operation, want string) (int, *openapi3.Response, error) {
+	if op.Responses == nil || op.Responses.Len() == 0 {
+		return http.StatusNoContent, nil, nil
+	}
+	responses := op.Responses.Map()
+
+	lookup := func(code string) (int, *openapi3.Response, bool) {
+		ref, ok := responses[code]
+		if !ok || ref.Value == nil {
+			return 0, nil, false
+		}
+		n, err := strconv.Atoi(code)
+		if err != nil {
+			n = http.StatusOK
+		}
+		return n, ref.Value, true
+	}
+
+	if want != "" {
+		if n, resp, ok := lookup(want); ok {
+			return n, resp, nil
+		}
+		// an undeclared code may still be served through the default response
+		if n, err := strconv.Atoi(want); err == nil {
+			if _, resp, ok := lookup("default"); ok {
+				return n, resp, nil
+			}
+		}
+		return 0, nil, fmt.Errorf("operation %s declares no response %s", op.OperationID, want)
+	}
+
+	codes := make([]string, 0, len(responses))
+	for code := range responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		if strings.HasPrefix(code, "2") {
+			if n, resp, ok := lookup(code); ok {
+				return n, resp, nil
+			}
+		}
+	}
+	if n, resp, ok := lookup("default"); ok {
+		return n, resp, nil
+	}
+	n, resp, _ := lookup(codes[0])
+	return n, resp, nil
+}
+
+// negotiate picks the response media type that best satisfies Accept.
+func negotiate(accept string, content openapi3.Content) (string, *openapi3.MediaType, bool) {
+	types := make([]string, 0, len(content))
+	for ct := range content {
+		types = append(types, ct)
+	}
+	sort.Strings(types)
+	if len(types) == 0 {
+		return "", nil, true
+	}
+	if strings.TrimSpace(accept) == "" {
+		for _, ct := range types {
+			if strings.Contains(ct, "json") {
+				return ct, content[ct], true
+			}
+		}
+		return types[0], content[types[0]], true
+	}
+
+	type accepted struct {
+		typ string
+		q   float64
+	}
+	var ranges []accepted
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if qs, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(qs, 64); err == nil {
+				q = f
+			}
+		}
+		ranges = append(ranges, accepted{mt, q})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	for _, a := range ranges {
+		if a.q <= 0 {
+			continue
+		}
+		for _, ct := range types {
+			base, _, _ := mime.ParseMediaType(ct)
+			if base == "" {
+				base = ct
+			}
+			if mediaMatches(a.typ, base) {
+				return ct, content[ct], true
+			}
+		}
+	}
+	return "", nil, false
+}
+
+func mediaMatches(pattern, typ string) bool {
+	if pattern == "*/*" || pattern == typ {
+		return true
+	}
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(typ, strings.TrimSuffix(pattern, "*"))
+	}
+	return false
+}
+
+func writeMockResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	route *routers.Route,
+	params map[string]string,
+	status int,
+	resp *openapi3.Response,
+	prefs preferences,
+	seed int64,
+) {
+	if resp == nil {
+		w.WriteHeader(status)
+		return
+	}
+
+	ct, media, ok := negotiate(r.Header.Get("Accept"), resp.Content)
+	if !ok {
+		http.Error(w, "no acceptable representation", http.StatusNotAcceptable)
+		return
+	}
+
+	w.Header().Set("X-Docs-Mock-Operation", route.Operation.OperationID)
+	if media == nil {
+		w.WriteHeader(status)
+		return
+	}
+
+	rnd := rand.New(rand.NewSource(seed))
+	var payload interface{}
+	if v, ok := mediaExample(media, prefs.example); ok {
+		payload = v
+	} else if media.Schema != nil && media.Schema.Value != nil {
+		payload = synthesize(media.Schema.Value, rnd, 0)
+		echoPathParams(payload, params)
+	}
+
+	w.Header().Set("Content-Type", ct)
+	w.WriteHeader(status)
+	if payload == nil {
+		return
+	}
+	if s, ok := payload.(string); ok && !strings.Contains(ct, "json") {
+		_, _ = w.Write([]byte(s))
+		return
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(payload)
+}
+
+// echoPathParams copies path parameters into same-named top-level fields so
+// GET /pets/42 answers with "id": 42.
+func echoPathParams(payload interface{}, params map[string]string) {
+	obj, ok := payload.(map[string]interface{})
+	if !ok {
+		return
+	}
+	for name, val := range params {
+		cur, ok := obj[name]
+		if !ok {
+			continue
+		}
+		switch cur.(type) {
+		case int64:
+			if n, err := strconv.ParseInt(val, 10, 64); err == nil {
+				obj[name] = n
+			}
+		case float64:
+			if f, err := strconv.ParseFloat(val, 64); err == nil {
+				obj[name] = f
+			}
+		case string:
+			obj[name] = val
+		}
+	}
+}
+
+// mediaExample returns the named example, the first of examples, the media
+// type's example or the schema's example, in that order.
+func mediaExample(media *openapi3.MediaType, name string) (interface{}, bool) {
+	if name != "" {
+		if ex, ok := media.Examples[name]; ok && ex.Value != nil {
+			return ex.Value.Value, true
+		}
+	}
+	if len(media.Examples) > 0 {
+		names := make([]string, 0, len(media.Examples))
+		for n := range media.Examples {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		if ex := media.Examples[names[0]]; ex.Value != nil {
+			return ex.Value.Value, true
+		}
+	}
+	if media.Example != nil {
+		return media.Example, true
+	}
+	if media.Schema != nil && media.Schema.Value != nil && media.Schema.Value.Example != nil {
+		return media.Schema.Value.Example, true
+	}
+	return nil, false
+}
+
+// synthesize generates a value conforming to the schema.
+func synthesize(s *openapi3.Schema, rnd *rand.Rand, depth int) interface{} {
+	if s == nil || depth > maxMockDepth {
+		return nil
+	}
+	if s.Example != nil {
+		return s.Example
+	}
+	if s.Default != nil {
+		return s.Default
+	}
+	if len(s.Enum) > 0 {
+		return s.Enum[rnd.Intn(len(s.Enum))]
+	}
+	if len(s.AllOf) > 0 {
+		merged := map[string]interface{}{}
+		for _, ref := range s.AllOf {
+			if m, ok := synthesize(ref.Value, rnd, depth+1).(map[string]interface{}); ok {
+				for k, v := range m {
+					merged[k] = v
+				}
+			}
+		}
+		if len(s.Properties) > 0 {
+			if m, ok := synthesizeObject(s, rnd, depth).(map[string]interface{}); ok {
+				for k, v := range m {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+	if len(s.OneOf) > 0 {
+		return synthesize(s.OneOf[0].Value, rnd, depth+1)
+	}
+	if len(s.AnyOf) > 0 {
+		return synthesize(s.AnyOf[0].Value, rnd, depth+1)
+	}
+
+	switch {
+	case s.Type.Is("object") || (s.Type == nil && len(s.Properties) > 0):
+		return synthesizeObject(s, rnd, depth)
+	case s.Type.Is("array"):
+		n := 1 + rnd.Intn(2)
+		if int(s.MinItems) > n {
+			n = int(s.MinItems)
+		}
+		if s.MaxItems != nil && int(*s.MaxItems) < n {
+			n = int(*s.MaxItems)
+		}
+		items := make([]interface{}, 0, n)
+		for i := 0; i < n; i++ {
+			var item *openapi3.Schema
+			if s.Items != nil {
+				item = s.Items.Value
+			}
+			items = append(items, synthesize(item, rnd, depth+1))
+		}
+		return items
+	case s.Type.Is("integer"):
+		lo, hi := numberBounds(s, 1, 1000)
+		// keep the span within int64 so int64 min/max bounds don't overflow
+		lo = math.Max(math.Ceil(lo), -maxMockInt)
+		hi = math.Min(math.Floor(hi), maxMockInt)
+		if hi <= lo {
+			return int64(lo)
+		}
+		return int64(lo) + rnd.Int63n(int64(hi-lo)+1)
+	case s.Type.Is("number"):
+		lo, hi := numberBounds(s, 0, 1000)
+		return float64(int((lo+rnd.Float64()*(hi-lo))*100)) / 100
+	case s.Type.Is("boolean"):
+		return rnd.Intn(2) == 1
+	case s.Type.Is("string"):
+		return synthesizeString(s, rnd)
+	}
+	return nil
+}
+
+func synthesizeObject(s *openapi3.Schema, rnd *rand.Rand, depth int) interface{} {
+	out := make(map[string]interface{}, len(s.Properties))
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prop := s.Properties[name]
+		if prop.Value == nil || prop.Value.WriteOnly {
+			continue
+		}
+		out[name] = synthesize(prop.Value, rnd, depth+1)
+	}
+	return out
+}
+
+// maxMockInt is the largest integer a float64 holds exactly.
+const maxMockInt = 1 << 53
+
+func numberBounds(s *openapi3.Schema, lo, hi float64) (float64, float64) {
+	if s.Min != nil {
+		lo = *s.Min
+		if hi < lo {
+			hi = lo + 1000
+		}
+	}
+	if s.Max != nil {
+		hi = *s.Max
+		if lo > hi {
+			lo = hi
+		}
+	}
+	return lo, hi
+}
+
+var mockWords = []string{"alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel"}
+
+func synthesizeString(s *openapi3.Schema, rnd *rand.Rand) string {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(rnd.Intn(365*24)) * time.Hour)
+	switch s.Format {
+	case "date-time":
+		return base.Format(time.RFC3339)
+	case "date":
+		return base.Format("2006-01-02")
+	case "uuid":
+		b := make([]byte, 16)
+		rnd.Read(b)
+		b[6] = (b[6] & 0x0f) | 0x40
+		b[8] = (b[8] & 0x3f) | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	case "email":
+		return mockWords[rnd.Intn(len(mockWords))] + "@example.com"
+	case "uri", "url":
+		return "https://example.com/" + mockWords[rnd.Intn(len(mockWords))]
+	case "byte":
+		return "ZXhhbXBsZQ=="
+	}
+	str := mockWords[rnd.Intn(len(mockWords))]
+	for uint64(len(str)) < s.MinLength {
+		str += "-" + mockWords[rnd.Intn(len(mockWords))]
+	}
+	if s.MaxLength != nil && uint64(len(str)) > *s.MaxLength {
+		str = str[:*s.MaxLength]
+	}
+	return str
+}
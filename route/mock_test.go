@@ -0,0 +1,114 @@
+package route
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/require"
+)
+
+const mockSpec = `{
+  "openapi": "3.0.0",
+  "info": { "title": "Pets", "version": "1.0.0" },
+  "servers": [{ "url": "http://pets.test/v1" }],
+  "paths": {
+    "/pets/{id}": {
+      "get": {
+        "operationId": "getPet",
+        "parameters": [{ "name": "id", "in": "path", "required": true, "schema": { "type": "integer" } }],
+        "responses": {
+          "200": { "description": "OK", "content": { "application/json": { "schema": {
+            "type": "object",
+            "properties": {
+              "id": { "type": "integer" },
+              "name": { "type": "string" },
+              "born": { "type": "string", "format": "date" },
+              "tags": { "type": "array", "items": { "type": "string" } }
+            }
+          } } } },
+          "404": { "description": "Missing", "content": { "application/json": {
+            "examples": { "gone": { "value": { "error": "not found" } } }
+          } } }
+        }
+      }
+    },
+    "/pets/mine": {
+      "get": {
+        "operationId": "getMyPet",
+        "responses": { "200": { "description": "OK", "content": { "text/plain": { "example": "rex" } } } }
+      }
+    }
+  }
+}`
+
+func TestMockHandler(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "pets.json")
+	require.NoError(t, os.WriteFile(file, []byte(mockSpec), 0o644))
+	h := NewMockService(NewDocuments([]Spec{{Name: "pets", File: file}}), false).MockHandler()
+
+	get := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+
+	first := get("/mock/pets/pets/42", nil)
+	require.Equal(t, http.StatusOK, first.Code)
+	require.Equal(t, "getPet", first.Header().Get("X-Docs-Mock-Operation"))
+	var pet map[string]interface{}
+	require.NoError(t, json.Unmarshal(first.Body.Bytes(), &pet))
+	require.Equal(t, float64(42), pet["id"])
+	require.IsType(t, "", pet["name"])
+
+	// same request, same payload
+	require.Equal(t, first.Body.String(), get("/mock/pets/pets/42", nil).Body.String())
+	require.NotEqual(t, first.Body.String(), get("/mock/pets/pets/42", http.Header{"Prefer": {"seed=7"}}).Body.String())
+
+	missing := get("/mock/pets/pets/42", http.Header{"Prefer": {"code=404"}})
+	require.Equal(t, http.StatusNotFound, missing.Code)
+	require.JSONEq(t, `{"error":"not found"}`, missing.Body.String())
+
+	// literal segments win over templates
+	mine := get("/mock/pets/pets/mine", nil)
+	require.Equal(t, http.StatusOK, mine.Code)
+	require.Equal(t, "rex", mine.Body.String())
+
+	require.Equal(t, http.StatusNotAcceptable, get("/mock/pets/pets/mine", http.Header{"Accept": {"application/xml"}}).Code)
+	require.Equal(t, http.StatusNotFound, get("/mock/pets/owners", nil).Code)
+}
+
+func TestSynthesizeIntegerBounds(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	f := func(v float64) *float64 { return &v }
+	cases := []struct {
+		min, max *float64
+		lo, hi   int64
+	}{
+		{f(math.MinInt64), f(math.MaxInt64), -maxMockInt, maxMockInt},
+		{f(0), f(math.MaxInt64), 0, maxMockInt},
+		{f(-1e300), nil, -maxMockInt, maxMockInt},
+		{f(1.5), f(3.5), 2, 3},
+		{f(5), f(5), 5, 5},
+	}
+	for _, c := range cases {
+		s := openapi3.NewIntegerSchema()
+		s.Min, s.Max = c.min, c.max
+		for i := 0; i < 20; i++ {
+			v, ok := synthesize(s, rnd, 0).(int64)
+			require.True(t, ok)
+			require.GreaterOrEqual(t, v, c.lo)
+			require.LessOrEqual(t, v, c.hi)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package route
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+)
+
+// mockCollection holds the resources created under one concrete collection
+// path, e.g. /owners/7/pets.
+type mockCollection struct {
+	items  map[string]map[string]interface{}
+	order  []string
+	nextID int
+}
+
+// MockStore is the in-memory backing store of the stateful mock mode.
+type MockStore struct {
+	mu    sync.Mutex
+	specs map[string]map[string]*mockCollection
+}
+
+func NewMockStore() *MockStore {
+	return &MockStore{specs: make(map[string]map[string]*mockCollection)}
+}
+
+func (m *MockStore) collection(spec, path string) *mockCollection {
+	spec = strings.ToLower(spec)
+	cols, ok := m.specs[spec]
+	if !ok {
+		cols = make(map[string]*mockCollection)
+		m.specs[spec] = cols
+	}
+	c, ok := cols[path]
+	if !ok {
+		c = &mockCollection{items: make(map[string]map[string]interface{})}
+		cols[path] = c
+	}
+	return c
+}
+
+// Reset clears one spec's resources, or every spec's when spec is empty.
+func (m *MockStore) Reset(spec string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if spec == "" {
+		m.specs = make(map[string]map[string]*mockCollection)
+		return
+	}
+	delete(m.specs, strings.ToLower(spec))
+}
+
+// snapshot copies one spec's resources so they can be encoded without
+// holding the lock.
+func (m *MockStore) snapshot(spec string) map[string][]map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string][]map[string]interface{})
+	for path, c := range m.specs[strings.ToLower(spec)] {
+		list := make([]map[string]interface{}, 0, len(c.order))
+		for _, id := range c.order {
+			item := make(map[string]interface{}, len(c.items[id]))
+			for k, v := range c.items[id] {
+				item[k] = v
+			}
+			list = append(list, item)
+		}
+		out[path] = list
+	}
+	return out
+}
+
+// crudShape describes how an operation maps onto the store.
+type crudShape struct {
+	collection string // concrete collection path
+	idParam    string // path param naming the resource id
+	id         string // concrete id, empty for collection operations
+}
+
+// itemParam returns the name of a single trailing {param} segment.
+func itemParam(tmpl string) (parent, param string, ok bool) {
+	i := strings.LastIndex(tmpl, "/")
+	if i < 0 {
+		return "", "", false
+	}
+	last := tmpl[i+1:]
+	if !strings.HasPrefix(last, "{") || !strings.HasSuffix(last, "}") {
+		return "", "", false
+	}
+	return tmpl[:i], last[1 : len(last)-1], true
+}
+
+func fillTemplate(tmpl string, params map[string]string) string {
+	for k, v := range params {
+		tmpl = strings.ReplaceAll(tmpl, "{"+k+"}", v)
+	}
+	return tmpl
+}
+
+// classify recognises collection (/pets) and item (/pets/{id}) operations of
+// a spec whose paths declare both.
+func classify(doc *openapi3.T, route *routers.Route, params map[string]string) (crudShape, bool) {
+	if parent, param, ok := itemParam(route.Path); ok && doc.Paths.Value(parent) != nil {
+		return crudShape{
+			collection: fillTemplate(parent, params),
+			idParam:    param,
+			id:         params[param],
+		}, true
+	}
+	for tmpl := range doc.Paths.Map() {
+		if parent, param, ok := itemParam(tmpl); ok && parent == route.Path {
+			return crudShape{collection: fillTemplate(route.Path, params), idParam: param}, true
+		}
+	}
+	return crudShape{}, false
+}
+
+// idField picks the body field that carries the id: the path param's name
+// when the schema or body uses it, otherwise "id".
+func idField(param string, body map[string]interface{}, schema *openapi3.Schema) string {
+	if _, ok := body[param]; ok {
+		return param
+	}
+	if schema != nil {
+		if _, ok := schema.Properties[param]; ok {
+			return param
+		}
+	}
+	return "id"
+}
+
+func requestSchema(op *openapi3.Operation) *openapi3.Schema {
+	if op.RequestBody == nil || op.RequestBody.Value == nil {
+		return nil
+	}
+	mt := op.RequestBody.Value.Content.Get("application/json")
+	if mt == nil || mt.Schema == nil {
+		return nil
+	}
+	return mt.Schema.Value
+}
+
+// firstDeclared returns the first of the codes the operation declares.
+func firstDeclared(op *openapi3.Operation, codes ...int) int {
+	for _, c := range codes {
+		if op.Responses != nil && op.Responses.Status(c) != nil {
+			return c
+		}
+	}
+	return codes[len(codes)-1]
+}
+
+// serveStateful answers CRUD operations from the store. It reports false for
+// operations that do not fit the collection/item pattern.
+func (s *MockService) serveStateful(w http.ResponseWriter, r *http.Request, route *routers.Route, params map[string]string) bool {
+	doc := route.Spec
+	shape, ok := classify(doc, route, params)
+	if !ok {
+		return false
+	}
+	spec := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/mock/"), "/", 2)[0]
+	op := route.Operation
+
+	// the body is read before taking the store lock so a slow client does
+	// not hold up every other collection
+	body := map[string]interface{}{}
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "failed to read body: "+err.Error(), http.StatusBadRequest)
+			return true
+		}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &body); err != nil {
+				http.Error(w, "body must be a JSON object: "+err.Error(), http.StatusBadRequest)
+				return true
+			}
+			// a literal null decodes without error into a nil map
+			if body == nil {
+				http.Error(w, "body must be a JSON object", http.StatusBadRequest)
+				return true
+			}
+		}
+	}
+
+	s.Store.mu.Lock()
+	defer s.Store.mu.Unlock()
+	col := s.Store.collection(spec, shape.collection)
+
+	switch {
+	case shape.id == "" && r.Method == http.MethodPost:
+		obj := body
+		field := idField(shape.idParam, obj, requestSchema(op))
+		var id string
+		if v, ok := obj[field]; ok {
+			id = strings.Trim(jsonString(v), `"`)
+		} else {
+			// skip ids clients chose themselves
+			for {
+				col.nextID++
+				id = strconv.Itoa(col.nextID)
+				if _, taken := col.items[id]; !taken {
+					break
+				}
+			}
+			obj[field] = col.nextID
+		}
+		if _, exists := col.items[id]; !exists {
+			col.order = append(col.order, id)
+		}
+		col.items[id] = obj
+		w.Header().Set("Location", "/mock/"+spec+shape.collection+"/"+id)
+		writeJSON(w, firstDeclared(op, http.StatusCreated, http.StatusOK), obj)
+
+	case shape.id == "" && r.Method == http.MethodGet:
+		list := make([]interface{}, 0, len(col.order))
+		for _, id := range col.order {
+			list = append(list, col.items[id])
+		}
+		writeJSON(w, http.StatusOK, wrapList(op, list))
+
+	case shape.id != "" && r.Method == http.MethodGet:
+		obj, ok := col.items[shape.id]
+		if !ok {
+			http.Error(w, "resource not found", http.StatusNotFound)
+			return true
+		}
+		writeJSON(w, http.StatusOK, obj)
+
+	case shape.id != "" && (r.Method == http.MethodPut || r.Method == http.MethodPatch):
+		cur, ok := col.items[shape.id]
+		if !ok {
+			http.Error(w, "resource not found", http.StatusNotFound)
+			return true
+		}
+		obj := body
+		if r.Method == http.MethodPatch {
+			// stored items are never changed in place, so snapshots taken
+			// for /mock-state stay safe to encode after the lock is released
+			obj = make(map[string]interface{}, len(cur)+len(body))
+			for k, v := range cur {
+				obj[k] = v
+			}
+			for k, v := range body {
+				obj[k] = v
+			}
+		} else {
+			field := idField(shape.idParam, cur, requestSchema(op))
+			obj[field] = cur[field]
+		}
+		col.items[shape.id] = obj
+		writeJSON(w, http.StatusOK, obj)
+
+	case shape.id != "" && r.Method == http.MethodDelete:
+		if _, ok := col.items[shape.id]; !ok {
+			http.Error(w, "resource not found", http.StatusNotFound)
+			return true
+		}
+		delete(col.items, shape.id)
+		for i, id := range col.order {
+			if id == shape.id {
+				col.order = append(col.order[:i], col.order[i+1:]...)
+				break
+			}
+		}
+		w.WriteHeader(firstDeclared(op, http.StatusNoContent, http.StatusOK))
+
+	default:
+		return false
+	}
+	return true
+}
+
+// wrapList shapes a list like the 200 response: a bare array, or an object
+// whose first array property carries the items.
+func wrapList(op *openapi3.Operation, list []interface{}) interface{} {
+	resp := op.Responses.Status(http.StatusOK)
+	if resp == nil || resp.Value == nil {
+		return list
+	}
+	mt := resp.Value.Content.Get("application/json")
+	if mt == nil || mt.Schema == nil || mt.Schema.Value == nil || !mt.Schema.Value.Type.Is("object") {
+		return list
+	}
+	for name, prop := range mt.Schema.Value.Properties {
+		if prop.Value != nil && prop.Value.Type.Is("array") {
+			return map[string]interface{}{name: list}
+		}
+	}
+	return list
+}
+
+func jsonString(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// MockStateHandler inspects (GET) or resets (DELETE) the stateful mock store.
+//
+// /mock-state/{spec}; DELETE /mock-state/ resets every spec.
+func (s *MockService) MockStateHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.Store == nil {
+			http.Error(w, "stateful mocking is disabled", http.StatusNotFound)
+			return
+		}
+		spec := strings.Trim(strings.TrimPrefix(r.URL.Path, "/mock-state"), "/")
+		switch r.Method {
+		case http.MethodDelete:
+			s.Store.Reset(spec)
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, s.Store.snapshot(spec))
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package route
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const crudSpec = `{
+  "openapi": "3.0.0",
+  "info": { "title": "Pets", "version": "1.0.0" },
+  "paths": {
+    "/pets": {
+      "get": {
+        "responses": { "200": { "description": "OK", "content": { "application/json": { "schema": {
+          "type": "object", "properties": { "items": { "type": "array", "items": { "type": "object" } } }
+        } } } } }
+      },
+      "post": {
+        "requestBody": { "content": { "application/json": { "schema": {
+          "type": "object", "properties": { "petId": { "type": "integer" }, "name": { "type": "string" } }
+        } } } },
+        "responses": { "201": { "description": "Created" } }
+      }
+    },
+    "/pets/{petId}": {
+      "parameters": [{ "name": "petId", "in": "path", "required": true, "schema": { "type": "integer" } }],
+      "get": { "responses": { "200": { "description": "OK" } } },
+      "put": { "responses": { "200": { "description": "OK" } } },
+      "patch": { "responses": { "200": { "description": "OK" } } },
+      "delete": { "responses": { "204": { "description": "Deleted" } } }
+    }
+  }
+}`
+
+func TestStatefulMock(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "pets.json")
+	require.NoError(t, os.WriteFile(file, []byte(crudSpec), 0o644))
+	svc := NewMockService(NewDocuments([]Spec{{Name: "pets", File: file}}), true)
+	h := svc.MockHandler()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+	decode := func(rec *httptest.ResponseRecorder) map[string]interface{} {
+		var v map[string]interface{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &v))
+		return v
+	}
+
+	rec := do(http.MethodPost, "/mock/pets/pets", `{"name":"rex"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, "/mock/pets/pets/1", rec.Header().Get("Location"))
+	require.EqualValues(t, 1, decode(rec)["petId"])
+
+	rec = do(http.MethodGet, "/mock/pets/pets/1", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "rex", decode(rec)["name"])
+
+	rec = do(http.MethodPut, "/mock/pets/pets/1", `{"name":"max"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, map[string]interface{}{"petId": 1.0, "name": "max"}, decode(rec))
+
+	rec = do(http.MethodGet, "/mock/pets/pets", "")
+	require.Len(t, decode(rec)["items"], 1)
+
+	rec = do(http.MethodDelete, "/mock/pets/pets/1", "")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Equal(t, http.StatusNotFound, do(http.MethodGet, "/mock/pets/pets/1", "").Code)
+
+	// generated ids skip ones clients picked
+	do(http.MethodPost, "/mock/pets/pets", `{"petId":2,"name":"fido"}`)
+	rec = do(http.MethodPost, "/mock/pets/pets", `{"name":"bella"}`)
+	require.Equal(t, "/mock/pets/pets/3", rec.Header().Get("Location"))
+	require.Equal(t, "fido", decode(do(http.MethodGet, "/mock/pets/pets/2", ""))["name"])
+
+	// null is valid JSON but not an object
+	require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/mock/pets/pets", `null`).Code)
+	require.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/mock/pets/pets/2", `null`).Code)
+
+	do(http.MethodPost, "/mock/pets/pets", `{"petId":7}`)
+	reset := httptest.NewRecorder()
+	svc.MockStateHandler()(reset, httptest.NewRequest(http.MethodDelete, "/mock-state/pets", nil))
+	require.Equal(t, http.StatusNoContent, reset.Code)
+	require.Empty(t, decode(do(http.MethodGet, "/mock/pets/pets", ""))["items"])
+}
+
+func TestStatefulMockPatchWhileInspecting(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "pets.json")
+	require.NoError(t, os.WriteFile(file, []byte(crudSpec), 0o644))
+	svc := NewMockService(NewDocuments([]Spec{{Name: "pets", File: file}}), true)
+	h, state := svc.MockHandler(), svc.MockStateHandler()
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/mock/pets/pets", strings.NewReader(`{"name":"rex"}`)))
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	// run with -race: /mock-state encodes its snapshot outside the store lock
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			body := strings.NewReader(`{"name":"rex-` + strconv.Itoa(i) + `"}`)
+			h(httptest.NewRecorder(), httptest.NewRequest(http.MethodPatch, "/mock/pets/pets/1", body))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			state(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/mock-state/pets", nil))
+		}
+	}()
+	wg.Wait()
+
+	rec = httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/mock/pets/pets/1", nil))
+	require.JSONEq(t, `{"petId":1,"name":"rex-199"}`, rec.Body.String())
+}
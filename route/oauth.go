@@ -0,0 +1,311 @@
+package route
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// OAuthClient registers better-docs as a client of one of a spec's
+// authorizationCode security schemes.
+type OAuthClient struct {
+	ClientID    string   `json:"clientId"`
+	Secret      string   `json:"secret,omitempty"` // secret name, for confidential clients
+	Scopes      []string `json:"scopes,omitempty"`
+	RedirectURL string   `json:"redirectUrl,omitempty"`
+}
+
+const pendingAuthTTL = 10 * time.Minute
+
+type pendingAuth struct {
+	spec, scheme string
+	session      string
+	verifier     string
+	redirectURI  string
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	deliver      string
+	created      time.Time
+}
+
+type OAuthService struct {
+	Specs    []Spec
+	Secrets  *SecretStore
+	Client   *http.Client
+	Sessions *SessionStore
+
+	mu      sync.Mutex
+	pending map[string]pendingAuth
+}
+
+func NewOAuthService(specs []Spec, secrets *SecretStore, client *http.Client, sessions *SessionStore) *OAuthService {
+	return &OAuthService{
+		Specs:    specs,
+		Secrets:  secrets,
+		Client:   client,
+		Sessions: sessions,
+		pending:  make(map[string]pendingAuth),
+	}
+}
+
+func (s *OAuthService) findSpec(name string) (Spec, bool) {
+	for _, sp := range s.Specs {
+		if strings.EqualFold(sp.Name, name) {
+			return sp, true
+		}
+	}
+	return Spec{}, false
+}
+
+// authorizationCodeFlow looks up the named security scheme in the spec file.
+func authorizationCodeFlow(file, scheme string) (*openapi3.OAuthFlow, error) {
+	doc, err := openapi3.NewLoader().LoadFromFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("load spec: %w", err)
+	}
+	if doc.Components == nil {
+		return nil, fmt.Errorf("spec has no securitySchemes")
+	}
+	ref, ok := doc.Components.SecuritySchemes[scheme]
+	if !ok || ref.Value == nil {
+		return nil, fmt.Errorf("security scheme %q not found", scheme)
+	}
+	if ref.Value.Flows == nil || ref.Value.Flows.AuthorizationCode == nil {
+		return nil, fmt.Errorf("security scheme %q has no authorizationCode flow", scheme)
+	}
+	return ref.Value.Flows.AuthorizationCode, nil
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (s *OAuthService) addPending(state string, p pendingAuth) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range s.pending {
+		if time.Since(v.created) > pendingAuthTTL {
+			delete(s.pending, k)
+		}
+	}
+	s.pending[state] = p
+}
+
+func (s *OAuthService) takePending(state string) (pendingAuth, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.pending[state]
+	delete(s.pending, state)
+	if ok && time.Since(p.created) > pendingAuthTTL {
+		return p, false
+	}
+	return p, ok
+}
+
+// StartHandler begins an authorization-code + PKCE flow.
+//
+// GET /oauth/start?spec={spec}&scheme={securityScheme}[&deliver=proxy|ui]
+func (s *OAuthService) StartHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		spec, ok := s.findSpec(q.Get("spec"))
+		if !ok {
+			http.Error(w, "spec not found", http.StatusNotFound)
+			return
+		}
+		schemeName := q.Get("scheme")
+		client, ok := spec.OAuthClients[schemeName]
+		if !ok || client.ClientID == "" {
+			http.Error(w, fmt.Sprintf("no oauth client configured for scheme %q", schemeName), http.StatusBadRequest)
+			return
+		}
+		flow, err := authorizationCodeFlow(spec.File, schemeName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var clientSecret string
+		if client.Secret != "" {
+			v, ok := s.Secrets.Lookup(client.Secret)
+			if !ok {
+				http.Error(w, fmt.Sprintf("secret %q not found", client.Secret), http.StatusInternalServerError)
+				return
+			}
+			clientSecret = v
+		}
+
+		scopes := client.Scopes
+		if len(scopes) == 0 {
+			for sc := range flow.Scopes {
+				scopes = append(scopes, sc)
+			}
+		}
+
+		redirectURI := client.RedirectURL
+		if redirectURI == "" {
+			redirectURI = requestBaseURL(r) + "/oauth/callback"
+		}
+
+		deliver := q.Get("deliver")
+		if deliver != "ui" {
+			deliver = "proxy"
+		}
+
+		state := randomToken(16)
+		verifier := randomToken(32)
+		s.addPending(state, pendingAuth{
+			spec:         spec.Name,
+			scheme:       schemeName,
+			session:      SessionID(w, r),
+			verifier:     verifier,
+			redirectURI:  redirectURI,
+			tokenURL:     flow.TokenURL,
+			clientID:     client.ClientID,
+			clientSecret: clientSecret,
+			deliver:      deliver,
+			created:      time.Now(),
+		})
+
+		authURL, err := url.Parse(flow.AuthorizationURL)
+		if err != nil {
+			http.Error(w, "invalid authorizationUrl: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		aq := authURL.Query()
+		aq.Set("response_type", "code")
+		aq.Set("client_id", client.ClientID)
+		aq.Set("redirect_uri", redirectURI)
+		aq.Set("state", state)
+		aq.Set("code_challenge", pkceChallenge(verifier))
+		aq.Set("code_challenge_method", "S256")
+		if len(scopes) > 0 {
+			aq.Set("scope", strings.Join(scopes, " "))
+		}
+		authURL.RawQuery = aq.Encode()
+
+		http.Redirect(w, r, authURL.String(), http.StatusFound)
+	}
+}
+
+var callbackPage = template.Must(template.New("callback").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="UTF-8" /><title>Signed in</title></head>
+<body>
+<p>Signed in to {{.Spec}}. You can close this window.</p>
+<script>
+    (() => {
+        const msg = {type: 'better-docs-oauth', spec: {{.Spec}}, scheme: {{.Scheme}}, accessToken: {{.AccessToken}}};
+        if (window.opener) {
+            window.opener.postMessage(msg, window.location.origin);
+            window.close();
+        } else {
+            window.location.replace({{.Return}});
+        }
+    })();
+</script>
+</body>
+</html>
+`))
+
+// CallbackHandler completes the flow: it exchanges the code using the PKCE
+// verifier and stores the token in the session, or hands it to the UI.
+//
+// GET /oauth/callback?code=...&state=...
+func (s *OAuthService) CallbackHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if e := q.Get("error"); e != "" {
+			http.Error(w, "authorization failed: "+e+" "+q.Get("error_description"), http.StatusBadRequest)
+			return
+		}
+		p, ok := s.takePending(q.Get("state"))
+		if !ok {
+			http.Error(w, "unknown or expired state", http.StatusBadRequest)
+			return
+		}
+		if requestSessionID(r) != p.session {
+			http.Error(w, "session mismatch", http.StatusBadRequest)
+			return
+		}
+
+		form := url.Values{}
+		form.Set("grant_type", "authorization_code")
+		form.Set("code", q.Get("code"))
+		form.Set("redirect_uri", p.redirectURI)
+		form.Set("client_id", p.clientID)
+		form.Set("code_verifier", p.verifier)
+		if p.clientSecret != "" {
+			form.Set("client_secret", p.clientSecret)
+		}
+		tr, err := requestToken(r.Context(), s.Client, p.tokenURL, form)
+		if err != nil {
+			http.Error(w, "token exchange failed: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		data := struct {
+			Spec, Scheme, AccessToken, Return string
+		}{p.spec, p.scheme, "", "/specs/" + p.spec + "/"}
+
+		if p.deliver == "ui" {
+			data.AccessToken = tr.AccessToken
+		} else {
+			s.Sessions.Put(p.session, p.spec, &sessionToken{
+				AccessToken:  tr.AccessToken,
+				RefreshToken: tr.RefreshToken,
+				TokenType:    tr.TokenType,
+				Expires:      tr.expiry(),
+				TokenURL:     p.tokenURL,
+				ClientID:     p.clientID,
+				ClientSecret: p.clientSecret,
+			})
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := callbackPage.Execute(w, data); err != nil {
+			http.Error(w, "failed to write response: "+err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+// SessionHandler reports (GET) or discards (DELETE) the session's token for a spec.
+//
+// /oauth/session?spec={spec}
+func (s *OAuthService) SessionHandler() http.HandlerFunc {
+	type response struct {
+		Spec          string `json:"spec"`
+		Authenticated bool   `json:"authenticated"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		spec := r.URL.Query().Get("spec")
+		session := requestSessionID(r)
+
+		switch r.Method {
+		case http.MethodDelete:
+			s.Sessions.Delete(session, spec)
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodGet:
+			_, ok := s.Sessions.Token(r.Context(), session, spec)
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(response{spec, ok}); err != nil {
+				http.Error(w, "failed to write response: "+err.Error(), http.StatusInternalServerError)
+			}
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package route
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAuthServer issues one code per authorize call and checks the PKCE
+// verifier on exchange.
+func fakeAuthServer(t *testing.T) *httptest.Server {
+	challenges := map[string]string{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		require.Equal(t, "code", q.Get("response_type"))
+		require.Equal(t, "S256", q.Get("code_challenge_method"))
+		challenges["the-code"] = q.Get("code_challenge")
+		cb, _ := url.Parse(q.Get("redirect_uri"))
+		cq := cb.Query()
+		cq.Set("code", "the-code")
+		cq.Set("state", q.Get("state"))
+		cb.RawQuery = cq.Encode()
+		http.Redirect(w, r, cb.String(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "authorization_code", r.PostForm.Get("grant_type"))
+		if pkceChallenge(r.PostForm.Get("code_verifier")) != challenges[r.PostForm.Get("code")] {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "user-token", "token_type": "Bearer", "expires_in": 3600,
+		})
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestOAuthAuthorizationCodeFlow(t *testing.T) {
+	as := fakeAuthServer(t)
+	defer as.Close()
+
+	specJSON := `{
+	  "openapi": "3.0.0",
+	  "info": { "title": "Secured", "version": "1.0.0" },
+	  "paths": {},
+	  "components": { "securitySchemes": { "corp": {
+	    "type": "oauth2",
+	    "flows": { "authorizationCode": {
+	      "authorizationUrl": "` + as.URL + `/authorize",
+	      "tokenUrl": "` + as.URL + `/token",
+	      "scopes": { "read": "read access" }
+	    } }
+	  } } }
+	}`
+	file := filepath.Join(t.TempDir(), "secured.json")
+	require.NoError(t, os.WriteFile(file, []byte(specJSON), 0o644))
+
+	specs := []Spec{{Name: "secured", File: file, OAuthClients: map[string]OAuthClient{"corp": {ClientID: "docs"}}}}
+	secrets, _ := LoadSecretStore("")
+	sessions := NewSessionStore(as.Client())
+	svc := NewOAuthService(specs, secrets, as.Client(), sessions)
+
+	// start → authorization server
+	rec := httptest.NewRecorder()
+	svc.StartHandler()(rec, httptest.NewRequest(http.MethodGet, "http://docs.test/oauth/start?spec=secured&scheme=corp", nil))
+	require.Equal(t, http.StatusFound, rec.Code)
+	cookie := rec.Result().Cookies()[0]
+
+	noFollow := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := noFollow.Get(rec.Header().Get("Location"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	// authorization server → callback
+	cbReq := httptest.NewRequest(http.MethodGet, resp.Header.Get("Location"), nil)
+	cbReq.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	svc.CallbackHandler()(rec, cbReq)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	tok, ok := sessions.Token(cbReq.Context(), cookie.Value, "secured")
+	require.True(t, ok)
+	require.Equal(t, "user-token", tok)
+
+	// a replayed state is rejected
+	rec = httptest.NewRecorder()
+	svc.CallbackHandler()(rec, cbReq)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestStripSessionCookie(t *testing.T) {
+	h := http.Header{}
+	h.Add("Cookie", "a=1; "+sessionCookie+"=secret; b=2")
+	stripSessionCookie(h)
+	require.Equal(t, "a=1; b=2", h.Get("Cookie"))
+}
@@ -0,0 +1,70 @@
+package route
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"better-docs/noproxy"
+)
+
+// proxyDirect as the proxy URL bypasses any proxy, including one configured
+// through HTTP_PROXY and friends.
+const proxyDirect = "direct"
+
+// ProxyConfig routes a spec's upstream calls through an HTTP, HTTPS or
+// SOCKS5 jump proxy. NoProxy lists hosts that bypass it, with the usual
+// no_proxy semantics: "*", exact hosts, domain suffixes ("example.com" and
+// ".example.com" both match sub.example.com), IPs and CIDRs, each with an
+// optional ":port".
+type ProxyConfig struct {
+	URL     string   `json:"url"`
+	NoProxy []string `json:"noProxy,omitempty"`
+}
+
+func (c *ProxyConfig) proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	if strings.EqualFold(c.URL, proxyDirect) {
+		return nil, nil
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy url %q: %w", c.URL, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy url %q has no host", c.URL)
+	}
+	noProxy := c.NoProxy
+	return func(req *http.Request) (*url.URL, error) {
+		if noproxy.Match(noProxy, req.URL) {
+			return nil, nil
+		}
+		return u, nil
+	}, nil
+}
+
+// proxyHosts returns the host of every jump proxy in specs, which the
+// SSRF guard must let the transport dial.
+func proxyHosts(specs []Spec) []string {
+	var out []string
+	add := func(c *ProxyConfig) {
+		if c == nil {
+			return
+		}
+		if u, err := url.Parse(c.URL); err == nil && u.Host != "" {
+			out = append(out, strings.ToLower(u.Host))
+		}
+	}
+	for _, s := range specs {
+		add(s.OutboundProxy)
+		for _, e := range s.Environments {
+			add(e.OutboundProxy)
+		}
+	}
+	return out
+}
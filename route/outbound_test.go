@@ -0,0 +1,66 @@
+package route
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecTransportUsesOutboundProxy(t *testing.T) {
+	var viaProxy []string
+	jump := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a forward proxy receives the absolute target URL
+		viaProxy = append(viaProxy, r.URL.String())
+		_, _ = io.WriteString(w, "proxied")
+	}))
+	defer jump.Close()
+	direct := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "direct")
+	}))
+	defer direct.Close()
+
+	directHost := strings.TrimPrefix(direct.URL, "http://")
+	specs := []Spec{{
+		Name:          "legacy",
+		OutboundProxy: &ProxyConfig{URL: jump.URL, NoProxy: []string{directHost}},
+	}}
+	hosts := map[string]string{"legacy.corp.test": "legacy", directHost: "legacy"}
+	tr, err := NewSpecTransport(http.DefaultTransport.(*http.Transport).Clone(), specs, hosts)
+	require.NoError(t, err)
+	client := &http.Client{Transport: tr}
+
+	get := func(u string) string {
+		resp, err := client.Get(u)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return string(b)
+	}
+	require.Equal(t, "proxied", get("http://legacy.corp.test/ping"))
+	require.Equal(t, []string{"http://legacy.corp.test/ping"}, viaProxy)
+	require.Equal(t, "direct", get(direct.URL+"/ping"))
+
+	// every member of an environment's pool gets its settings
+	viaProxy = nil
+	tr, err = NewSpecTransport(http.DefaultTransport.(*http.Transport).Clone(), []Spec{{
+		Name: "orders",
+		Environments: []Environment{{
+			Name:          "dc",
+			ProxyBases:    []string{"http://a.orders.test", "http://b.orders.test"},
+			OutboundProxy: &ProxyConfig{URL: jump.URL},
+		}},
+	}}, nil)
+	require.NoError(t, err)
+	client = &http.Client{Transport: tr}
+	require.Equal(t, "proxied", get("http://a.orders.test/ping"))
+	require.Equal(t, "proxied", get("http://b.orders.test/ping"))
+	require.Equal(t, []string{"http://a.orders.test/ping", "http://b.orders.test/ping"}, viaProxy)
+
+	_, err = NewSpecTransport(http.DefaultTransport.(*http.Transport).Clone(),
+		[]Spec{{Name: "bad", OutboundProxy: &ProxyConfig{URL: "ftp://jump"}}}, hosts)
+	require.Error(t, err)
+}
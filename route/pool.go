@@ -0,0 +1,306 @@
+package route
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultHealthInterval = 30 * time.Second
+
+// HealthCheck configures active health checks of a spec's upstream pool.
+type HealthCheck struct {
+	Path     string `json:"path,omitempty"`     // relative to each base; default the base itself
+	Interval string `json:"interval,omitempty"` // default 30s
+}
+
+// poolMember is one base URL of an upstream pool.
+type poolMember struct {
+	Base      string    `json:"base"`
+	Healthy   bool      `json:"healthy"`
+	Failures  int       `json:"failures"`
+	LastCheck time.Time `json:"lastCheck,omitempty"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+// Pool is the set of interchangeable bases an Upstream can forward to. The
+// proxy tries healthy members in declaration order and fails over to the
+// next one on connection errors and 5xx responses.
+type Pool struct {
+	check    *HealthCheck
+	interval time.Duration
+
+	mu      sync.Mutex
+	members []*poolMember
+}
+
+func newPool(bases []string, check *HealthCheck) (*Pool, error) {
+	p := &Pool{check: check, interval: defaultHealthInterval}
+	seen := make(map[string]bool)
+	for _, b := range bases {
+		b = strings.TrimRight(b, "/")
+		if b == "" || seen[b] {
+			continue
+		}
+		seen[b] = true
+		p.members = append(p.members, &poolMember{Base: b, Healthy: true})
+	}
+	if check != nil && check.Interval != "" {
+		d, err := time.ParseDuration(check.Interval)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid health check interval %q", check.Interval)
+		}
+		p.interval = d
+	}
+	return p, nil
+}
+
+// Bases returns every member base in declaration order.
+func (p *Pool) Bases() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]string, len(p.members))
+	for i, m := range p.members {
+		out[i] = m.Base
+	}
+	return out
+}
+
+// candidates orders the bases for one call: healthy members first, then
+// unhealthy ones as a last resort.
+func (p *Pool) candidates() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var healthy, down []string
+	for _, m := range p.members {
+		if m.Healthy {
+			healthy = append(healthy, m.Base)
+		} else {
+			down = append(down, m.Base)
+		}
+	}
+	return append(healthy, down...)
+}
+
+func (p *Pool) mark(base string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, m := range p.members {
+		if m.Base != base {
+			continue
+		}
+		if err != nil {
+			if m.Healthy {
+				log.Printf("upstream %s marked down: %v", base, err)
+			}
+			m.Healthy = false
+			m.Failures++
+			m.LastError = err.Error()
+		} else {
+			m.Healthy = true
+			m.Failures = 0
+			m.LastError = ""
+		}
+	}
+}
+
+func (p *Pool) status() []poolMember {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]poolMember, len(p.members))
+	for i, m := range p.members {
+		out[i] = *m
+	}
+	return out
+}
+
+// member returns the base target lies under and the remainder of target.
+func (p *Pool) member(target string) (string, string, bool) {
+	for _, b := range p.Bases() {
+		if rest, ok := strings.CutPrefix(target, b); ok && (rest == "" || strings.ContainsAny(rest[:1], "/?#")) {
+			return b, rest, true
+		}
+	}
+	return "", "", false
+}
+
+// checkAll probes every member once.
+func (p *Pool) checkAll(ctx context.Context, client *http.Client) {
+	path := ""
+	if p.check != nil && p.check.Path != "" {
+		path = "/" + strings.TrimLeft(p.check.Path, "/")
+	}
+	for _, base := range p.Bases() {
+		err := probe(ctx, client, base+path)
+		p.mu.Lock()
+		for _, m := range p.members {
+			if m.Base == base {
+				m.LastCheck = time.Now().UTC()
+			}
+		}
+		p.mu.Unlock()
+		p.mark(base, err)
+	}
+}
+
+func probe(ctx context.Context, client *http.Client, u string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("health check returned %s", resp.Status)
+	}
+	return nil
+}
+
+// StartHealthChecks probes every pool that has more than one member or an
+// explicit health check, until ctx is done.
+func (u *Upstreams) StartHealthChecks(ctx context.Context, client *http.Client) {
+	for _, up := range u.All() {
+		p := up.Pool
+		if p == nil || (len(p.members) < 2 && p.check == nil) {
+			continue
+		}
+		go func() {
+			t := time.NewTicker(p.interval)
+			defer t.Stop()
+			for {
+				p.checkAll(ctx, client)
+				select {
+				case <-ctx.Done():
+					return
+				case <-t.C:
+				}
+			}
+		}()
+	}
+}
+
+// send forwards req to the upstream, failing over across the pool on
+// connection errors and 5xx responses. Requests that are not idempotent only
+// move on when the member could not be dialled, so they never run twice. The
+// request body is buffered so it can be re-sent.
+func (s *ProxyService) send(client *http.Client, req *http.Request, up *Upstream) (*http.Response, error) {
+	if up == nil || up.Pool == nil || isUpgrade(req.Header) {
+		return client.Do(req)
+	}
+	base, rest, ok := up.Pool.member(req.URL.String())
+	if !ok {
+		return client.Do(req)
+	}
+	bases := up.Pool.candidates()
+	if len(bases) == 1 {
+		resp, err := client.Do(req)
+		up.Pool.mark(base, err)
+		return resp, err
+	}
+
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read request body: %w", err)
+		}
+		body = b
+	}
+
+	var resp *http.Response
+	var err error
+	for i, b := range bases {
+		attempt := req.Clone(req.Context())
+		if attempt.URL, err = attempt.URL.Parse(b + rest); err != nil {
+			return nil, err
+		}
+		attempt.Host = attempt.URL.Host
+		attempt.Header.Set("Origin", attempt.URL.Scheme+"://"+attempt.URL.Host)
+		attempt.Header.Set("Referer", attempt.URL.String())
+		if body != nil {
+			attempt.Body = io.NopCloser(bytes.NewReader(body))
+			attempt.ContentLength = int64(len(body))
+		}
+
+		resp, err = client.Do(attempt)
+		last := i == len(bases)-1
+		switch {
+		case err != nil:
+			up.Pool.mark(b, err)
+			if req.Context().Err() != nil || (!idempotent(req.Method) && !isDialError(err)) {
+				return nil, err
+			}
+		case resp.StatusCode >= 500 && !last && idempotent(req.Method):
+			up.Pool.mark(b, fmt.Errorf("upstream returned %s", resp.Status))
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		default:
+			if resp.StatusCode >= 500 {
+				up.Pool.mark(b, fmt.Errorf("upstream returned %s", resp.Status))
+			} else {
+				up.Pool.mark(b, nil)
+			}
+			return resp, nil
+		}
+	}
+	return resp, err
+}
+
+// upstreamHealth is one environment's entry in the /upstreams report.
+type upstreamHealth struct {
+	Spec    string       `json:"spec"`
+	Env     string       `json:"env"`
+	Members []poolMember `json:"members"`
+}
+
+// UpstreamsHandler reports pool health.
+//
+// GET /upstreams[/{spec}]
+func UpstreamsHandler(upstreams *Upstreams) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		spec := strings.ToLower(strings.Trim(strings.TrimPrefix(r.URL.Path, "/upstreams"), "/"))
+		out := []upstreamHealth{}
+		for _, up := range upstreams.All() {
+			if (spec != "" && up.Spec != spec) || up.Pool == nil {
+				continue
+			}
+			out = append(out, upstreamHealth{Spec: up.Spec, Env: up.Env, Members: up.Pool.status()})
+		}
+		if spec != "" && len(out) == 0 {
+			http.Error(w, "unknown spec", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+// idempotent reports whether a request may be sent again after it reached an
+// upstream (RFC 9110 section 9.2.2).
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// isDialError reports whether err happened before a connection was made, so
+// the upstream never saw the request.
+func isDialError(err error) bool {
+	var op *net.OpError
+	var dns *net.DNSError
+	return errors.As(err, &dns) || (errors.As(err, &op) && op.Op == "dial")
+}
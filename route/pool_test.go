@@ -0,0 +1,100 @@
+package route
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyFailsOverAcrossPool(t *testing.T) {
+	failed := 0
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failed++
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	var got []string
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got = append(got, r.URL.Path+" "+string(b))
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer healthy.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	upstreams, err := buildUpstreams([]Spec{{
+		Name:       "orders",
+		ProxyBase:  closed.URL + "/v1",
+		ProxyBases: []string{failing.URL + "/v1", healthy.URL + "/v1"},
+	}})
+	require.NoError(t, err)
+	guard, err := NewGuard(GuardConfig{}, nil)
+	require.NoError(t, err)
+	h := NewProxyService(upstreams, nil, http.DefaultClient, guard, nil, nil, nil, nil).ProxyHandler()
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPut, "/api/orders/items", strings.NewReader("payload")))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, []string{"/v1/items payload"}, got)
+	require.Equal(t, 1, failed)
+
+	// members that failed are tried last from now on
+	up, err := upstreams.Resolve("orders", "")
+	require.NoError(t, err)
+	require.Equal(t, healthy.URL+"/v1", up.Pool.candidates()[0])
+
+	rec = httptest.NewRecorder()
+	UpstreamsHandler(upstreams)(rec, httptest.NewRequest(http.MethodGet, "/upstreams/orders", nil))
+	var report []upstreamHealth
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	require.Len(t, report, 1)
+	require.Len(t, report[0].Members, 3)
+	require.False(t, report[0].Members[0].Healthy)
+	require.False(t, report[0].Members[1].Healthy)
+	require.True(t, report[0].Members[2].Healthy)
+
+	// a POST that reached a member is not sent again, but one that could not
+	// connect moves on
+	upstreams, err = buildUpstreams([]Spec{{
+		Name:       "orders",
+		ProxyBase:  closed.URL + "/v1",
+		ProxyBases: []string{failing.URL + "/v1", healthy.URL + "/v1"},
+	}})
+	require.NoError(t, err)
+	h = NewProxyService(upstreams, nil, http.DefaultClient, guard, nil, nil, nil, nil).ProxyHandler()
+	rec = httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/api/orders/items", strings.NewReader("payload")))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.Equal(t, 2, failed)
+	require.Len(t, got, 1)
+}
+
+func TestPoolHealthCheck(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/health", r.URL.Path)
+		w.WriteHeader(status)
+	}))
+	defer backend.Close()
+
+	p, err := newPool([]string{backend.URL + "/v1"}, &HealthCheck{Path: "health", Interval: "1m"})
+	require.NoError(t, err)
+	p.checkAll(context.Background(), http.DefaultClient)
+	require.False(t, p.status()[0].Healthy)
+	require.Contains(t, p.status()[0].LastError, "503")
+
+	status = http.StatusOK
+	p.checkAll(context.Background(), http.DefaultClient)
+	require.True(t, p.status()[0].Healthy)
+	require.False(t, p.status()[0].LastCheck.IsZero())
+
+	_, err = newPool(nil, &HealthCheck{Interval: "soon"})
+	require.Error(t, err)
+}
@@ -1,6 +1,8 @@
 package route
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
@@ -8,13 +10,51 @@ import (
 	"net/url"
 	"path"
 	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3filter"
 )
 
-func WithCORS(h http.HandlerFunc) http.HandlerFunc {
+// originAllowlist is the set of origins given with --cors-origin.
+type originAllowlist map[string]struct{}
+
+func newOriginAllowlist(origins []string) originAllowlist {
+	allowed := make(originAllowlist, len(origins))
+	for _, o := range origins {
+		allowed[strings.TrimRight(strings.TrimSpace(o), "/")] = struct{}{}
+	}
+	return allowed
+}
+
+func (a originAllowlist) any() bool {
+	_, ok := a["*"]
+	return ok
+}
+
+// allows reports whether origin was configured; "*" allows any origin.
+func (a originAllowlist) allows(origin string) bool {
+	_, ok := a[origin]
+	return a.any() || (origin != "" && ok)
+}
+
+// WithCORS allows cross-origin calls from the configured origins only; an
+// entry of "*" allows any origin. With no origins no CORS headers are sent.
+func WithCORS(origins []string, h http.HandlerFunc) http.HandlerFunc {
+	allowed := newOriginAllowlist(origins)
+	anyOrigin := allowed.any()
+
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
+		origin := r.Header.Get("Origin")
+		w.Header().Add("Vary", "Origin")
+		if allowed.allows(origin) {
+			if anyOrigin {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+		}
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+envHeader+", "+validateHeader+", "+faultSessionHeader)
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusNoContent)
 			return
@@ -23,17 +63,122 @@ func WithCORS(h http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-func ProxyHandler(proxyMap map[string]string, client *http.Client) http.HandlerFunc {
+type ProxyService struct {
+	Upstreams *Upstreams
+	Client    *http.Client
+	Guard     *Guard
+	// HostSpecs maps upstream hosts to spec names so ?url= calls made by the
+	// try-it panel pick up the same per-spec settings as /api/{spec}/.
+	HostSpecs map[string]string
+	Sessions  *SessionStore
+	Validator *Validator
+	Faults    *FaultInjector
+	Recorder  *Recorder
+	// Origins lists the cross-origin callers allowed to use upstreams that
+	// have server-side credentials; see checkOrigin.
+	Origins []string
+}
+
+// checkOrigin stops other sites from making a visitor's browser call an
+// upstream with the credentials the server adds. Requests without an Origin
+// (same-origin GETs, curl and friends) and same-origin requests pass.
+func (s *ProxyService) checkOrigin(r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	if u, err := url.Parse(origin); err == nil && u.Host == r.Host {
+		return nil
+	}
+	if newOriginAllowlist(s.Origins).allows(origin) {
+		return nil
+	}
+	return fmt.Errorf("origin %s may not use credentials for this spec", origin)
+}
+
+func NewProxyService(
+	upstreams *Upstreams,
+	hostSpecs map[string]string,
+	client *http.Client,
+	guard *Guard,
+	sessions *SessionStore,
+	validator *Validator,
+	faults *FaultInjector,
+	recorder *Recorder,
+) *ProxyService {
+	return &ProxyService{
+		Upstreams: upstreams,
+		Client:    client,
+		Guard:     guard,
+		HostSpecs: hostSpecs,
+		Sessions:  sessions,
+		Validator: validator,
+		Faults:    faults,
+		Recorder:  recorder,
+	}
+}
+
+// specForHost returns the spec registered for the target host, if any.
+func (s *ProxyService) specForHost(u *url.URL) string {
+	if name, ok := s.HostSpecs[strings.ToLower(u.Host)]; ok {
+		return name
+	}
+	return s.HostSpecs[strings.ToLower(u.Hostname())]
+}
+
+// upstreamForURL finds the environment a ?url= target belongs to. An explicit
+// environment selection moves the target onto that environment's host.
+func (s *ProxyService) upstreamForURL(target *url.URL, env string) *Upstream {
+	up := s.Upstreams.ForHost(target)
+	if up != nil && (env == "" || strings.EqualFold(env, up.Env)) {
+		return up
+	}
+	spec := s.specForHost(target)
+	if up != nil {
+		spec = up.Spec
+	}
+	if spec == "" {
+		return nil
+	}
+	sel, err := s.Upstreams.Resolve(spec, env)
+	if err != nil {
+		return nil
+	}
+	if env != "" {
+		if b, err := url.Parse(sel.Base); err == nil && b.Host != "" {
+			target.Scheme, target.Host = b.Scheme, b.Host
+		}
+	}
+	return sel
+}
+
+func (s *ProxyService) ProxyHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var target *url.URL
+		var up *Upstream
 		var err error
 
+		env := selectedEnv(r)
+		mode := s.Validator.modeFor(r)
+
 		if u := r.URL.Query().Get("url"); u != "" {
 			target, err = url.Parse(u)
 			if err != nil {
 				http.Error(w, "invalid target url: "+err.Error(), http.StatusBadRequest)
 				return
 			}
+			// WebSocket URLs are dialled over HTTP and upgraded
+			switch target.Scheme {
+			case "ws":
+				target.Scheme = "http"
+			case "wss":
+				target.Scheme = "https"
+			}
+			if err := s.Guard.CheckURL(target); err != nil {
+				http.Error(w, "target not allowed: "+err.Error(), http.StatusForbidden)
+				return
+			}
+			up = s.upstreamForURL(target, env)
 		} else {
 			// /api/{spec}/{path...}
 			parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/api/"), "/", 2)
@@ -41,20 +186,35 @@ func ProxyHandler(proxyMap map[string]string, client *http.Client) http.HandlerF
 				http.Error(w, "invalid API path", http.StatusBadRequest)
 				return
 			}
-			base, ok := proxyMap[strings.ToLower(parts[0])]
-			if !ok {
-				http.Error(w, fmt.Sprintf("no proxyBase for %s", parts[0]), http.StatusBadRequest)
+			up, err = s.Upstreams.Resolve(parts[0], env)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
 				return
 			}
-			baseURL, _ := url.Parse(base)
+			if up.Base == "" {
+				http.Error(w, fmt.Sprintf("no proxyBase for %s (%s)", parts[0], up.Env), http.StatusBadRequest)
+				return
+			}
+			baseURL, _ := url.Parse(up.Base)
 			rel := &url.URL{Path: path.Join(baseURL.Path, parts[1]), RawQuery: r.URL.RawQuery}
 			target = baseURL.ResolveReference(rel)
 		}
 
+		if up != nil && up.Auth != nil {
+			if err := s.checkOrigin(r); err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+		}
+
 		req := r.Clone(r.Context())
 		req.RequestURI = ""
 		req.Host = target.Host
-		req.URL = target
+		// auth may add a key to the query; target keeps the URL without it
+		// for logs, recordings and validation reports
+		sent := *target
+		req.URL = &sent
+		req.Header = make(http.Header, len(r.Header))
 
 		for k, v := range r.Header {
 			if h := strings.ToLower(k); h == "host" || h == "origin" || h == "referer" {
@@ -66,17 +226,139 @@ func ProxyHandler(proxyMap map[string]string, client *http.Client) http.HandlerF
 		}
 		req.Header.Set("Origin", target.Scheme+"://"+target.Host)
 		req.Header.Set("Referer", target.String())
+		stripSessionCookie(req.Header)
 
-		resp, err := client.Do(req)
+		var entry *RecordedEntry
+		if s.Recorder != nil && !isUpgrade(r.Header) {
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				http.Error(w, "failed to read request body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+			req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+			entry = &RecordedEntry{Request: RecordedMessage{
+				Method:  req.Method,
+				URL:     target.String(),
+				Headers: req.Header.Clone(),
+				Body:    body,
+			}}
+		}
+
+		var auth Authenticator
+		if up != nil {
+			for k, v := range up.Headers {
+				req.Header.Set(k, v)
+			}
+			if up.Rules != nil {
+				applyHeaderRules(up.Rules.Request, req.Header, up)
+			}
+			auth = up.Auth
+			if auth != nil {
+				if err := auth.Apply(req.Context(), req); err != nil {
+					http.Error(w, "failed to apply credentials: "+err.Error(), http.StatusBadGateway)
+					return
+				}
+			}
+			if tok, ok := s.Sessions.Token(req.Context(), requestSessionID(r), up.Spec); ok {
+				req.Header.Set("Authorization", "Bearer "+tok)
+			}
+		}
+
+		var report *ValidationReport
+		var vin *openapi3filter.RequestValidationInput
+		if up != nil && mode != ValidateOff {
+			report, vin = s.Validator.CheckRequest(req.Context(), req, up)
+			report.URL = target.String()
+			if mode == ValidateEnforce && len(report.Request) > 0 {
+				s.Validator.Store(report)
+				report.setHeaders(w.Header())
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(report)
+				return
+			}
+		}
+
+		fault := s.Faults.plan(r, req, up)
+		if fault != nil {
+			w.Header().Set(faultHeader, strings.Join(fault.ids, ","))
+			w.Header().Add("Access-Control-Expose-Headers", faultHeader)
+			if err := fault.delay(req.Context()); err != nil {
+				return
+			}
+			if fault.drop {
+				panic(http.ErrAbortHandler)
+			}
+			if fault.status != 0 {
+				http.Error(w, fault.body, fault.status)
+				return
+			}
+		}
+
+		start := time.Now()
+		// req carries the client's context, so a client going away cancels
+		// the upstream call and any stream it is relaying
+		resp, err := s.send(s.clientFor(r), req, up)
 		if err != nil {
 			http.Error(w, "error connecting to target: "+err.Error(), http.StatusBadGateway)
 			return
 		}
+		if auth != nil && resp.StatusCode == http.StatusUnauthorized {
+			auth.Invalidate()
+		}
 		log.Println("Proxying request to:", target.String())
 		defer resp.Body.Close()
 
+		if resp.StatusCode == http.StatusSwitchingProtocols {
+			if err := proxyUpgrade(w, resp); err != nil {
+				log.Printf("upgrade to %s: %v", target, err)
+			}
+			return
+		}
+
+		if isEventStream(resp) {
+			// events are relayed as they arrive; only the request side is checked
+			if report != nil {
+				s.Validator.Store(report)
+				report.setHeaders(w.Header())
+			}
+			if entry != nil {
+				entry.Time = start.UTC()
+				entry.Duration = time.Since(start)
+				entry.Response = RecordedMessage{Status: resp.StatusCode, Headers: resp.Header.Clone()}
+				s.Recorder.Record(entry, target, up)
+			}
+		} else if report != nil || entry != nil || (fault != nil && fault.corrupt) {
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				http.Error(w, "error reading target response: "+err.Error(), http.StatusBadGateway)
+				return
+			}
+			if report != nil {
+				s.Validator.CheckResponse(req.Context(), report, vin, resp.StatusCode, resp.Header, body)
+				s.Validator.Store(report)
+				report.setHeaders(w.Header())
+			}
+			if entry != nil {
+				entry.Time = start.UTC()
+				entry.Duration = time.Since(start)
+				entry.Response = RecordedMessage{Status: resp.StatusCode, Headers: resp.Header.Clone(), Body: body}
+				s.Recorder.Record(entry, target, up)
+			}
+			if fault != nil && fault.corrupt {
+				body = s.Faults.corruptBody(body)
+				resp.Header.Del("Content-Length")
+			}
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		if up != nil && up.Rules != nil {
+			applyHeaderRules(up.Rules.Response, resp.Header, up)
+		}
 		for k, v := range resp.Header {
-			if strings.ToLower(k) == "transfer-encoding" {
+			// upstream CORS headers would override the configured policy
+			if h := strings.ToLower(k); h == "transfer-encoding" || strings.HasPrefix(h, "access-control-") {
 				continue
 			}
 			for _, vv := range v {
@@ -84,6 +366,6 @@ func ProxyHandler(proxyMap map[string]string, client *http.Client) http.HandlerF
 			}
 		}
 		w.WriteHeader(resp.StatusCode)
-		io.Copy(w, resp.Body)
+		copyFlushing(w, resp.Body)
 	}
 }
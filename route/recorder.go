@@ -0,0 +1,411 @@
+package route
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"better-docs/indexing"
+	"github.com/blevesearch/bleve/v2"
+)
+
+const replayHeader = "X-Docs-Replay-Of"
+
+// redactedHeaders never reach the recording; credentials are injected again
+// by the proxy on replay.
+var redactedHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"set-cookie":          true,
+}
+
+// RecordedMessage is one side of a recorded exchange.
+type RecordedMessage struct {
+	Method  string      `json:"method,omitempty"`
+	URL     string      `json:"url,omitempty"`
+	Status  int         `json:"status,omitempty"`
+	Headers http.Header `json:"headers"`
+	Body    []byte      `json:"body,omitempty"`
+}
+
+// RecordedEntry is a proxied request/response pair tagged with the matched
+// spec and operation.
+type RecordedEntry struct {
+	ID          string          `json:"id"`
+	Time        time.Time       `json:"time"`
+	Duration    time.Duration   `json:"duration"`
+	Spec        string          `json:"spec,omitempty"`
+	Env         string          `json:"env,omitempty"`
+	OperationID string          `json:"operationId,omitempty"`
+	Request     RecordedMessage `json:"request"`
+	Response    RecordedMessage `json:"response"`
+}
+
+// Recorder stores proxied traffic as one JSON file per entry under Dir.
+type Recorder struct {
+	Dir      string
+	Index    bleve.Index
+	Registry indexing.Registry
+	Docs     *Documents
+
+	mu   sync.Mutex
+	last int64
+}
+
+// NewRecorder returns nil when dir is empty, which disables recording.
+func NewRecorder(dir string, idx bleve.Index, reg indexing.Registry, docs *Documents) (*Recorder, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create recording directory %q: %w", dir, err)
+	}
+	return &Recorder{Dir: dir, Index: idx, Registry: reg, Docs: docs}, nil
+}
+
+func recordedHeaders(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for k, v := range h {
+		if redactedHeaders[strings.ToLower(k)] {
+			continue
+		}
+		out[k] = append([]string(nil), v...)
+	}
+	return out
+}
+
+// operation tags the call with FindOperation, falling back to the spec
+// document for upstreams the search registry does not know.
+func (rec *Recorder) operation(method string, target *url.URL, up *Upstream) (string, string) {
+	if rec.Index != nil {
+		if spec, opID, _, err := indexing.FindOperation(rec.Index, rec.Registry, method, target.String()); err == nil {
+			return spec, opID
+		}
+	}
+	if up == nil {
+		return "", ""
+	}
+	var bases []string
+	if b, err := url.Parse(up.Base); err == nil {
+		bases = append(bases, b.Path)
+	}
+	route, _, err := rec.Docs.FindRoute(up.Spec, method, target.Path, bases...)
+	if err != nil {
+		return up.Spec, ""
+	}
+	return up.Spec, route.Operation.OperationID
+}
+
+// Record writes the exchange to disk. Failures are logged, never surfaced
+// to the proxied caller.
+func (rec *Recorder) Record(entry *RecordedEntry, target *url.URL, up *Upstream) {
+	if rec == nil {
+		return
+	}
+	entry.Spec, entry.OperationID = rec.operation(entry.Request.Method, target, up)
+	if up != nil {
+		entry.Env = up.Env
+	}
+	entry.Request.Headers = recordedHeaders(entry.Request.Headers)
+	entry.Response.Headers = recordedHeaders(entry.Response.Headers)
+
+	rec.mu.Lock()
+	id := entry.Time.UnixNano()
+	if id <= rec.last {
+		id = rec.last + 1
+	}
+	rec.last = id
+	rec.mu.Unlock()
+	entry.ID = strconv.FormatInt(id, 10)
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(rec.Dir, entry.ID+".json"), data, 0o644)
+	}
+	if err != nil {
+		log.Printf("record %s %s: %v", entry.Request.Method, entry.Request.URL, err)
+	}
+}
+
+func (rec *Recorder) load(id string) (*RecordedEntry, error) {
+	if strings.ContainsAny(id, `/\.`) {
+		return nil, os.ErrNotExist
+	}
+	data, err := os.ReadFile(filepath.Join(rec.Dir, id+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var e RecordedEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("decode recording %s: %w", id, err)
+	}
+	return &e, nil
+}
+
+// Entries returns the recordings, oldest first, optionally for one spec.
+func (rec *Recorder) Entries(spec string) ([]*RecordedEntry, error) {
+	files, err := filepath.Glob(filepath.Join(rec.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*RecordedEntry, 0, len(files))
+	for _, f := range files {
+		e, err := rec.load(strings.TrimSuffix(filepath.Base(f), ".json"))
+		if err != nil {
+			log.Printf("skip recording %s: %v", f, err)
+			continue
+		}
+		if spec != "" && !strings.EqualFold(e.Spec, spec) {
+			continue
+		}
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Time.Before(out[j].Time) })
+	return out, nil
+}
+
+// harNV is a HAR name/value pair.
+type harNV struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harRequest struct {
+	Method      string       `json:"method"`
+	URL         string       `json:"url"`
+	HTTPVersion string       `json:"httpVersion"`
+	Cookies     []harNV      `json:"cookies"`
+	Headers     []harNV      `json:"headers"`
+	QueryString []harNV      `json:"queryString"`
+	PostData    *harPostData `json:"postData,omitempty"`
+	HeadersSize int          `json:"headersSize"`
+	BodySize    int          `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harResponse struct {
+	Status      int        `json:"status"`
+	StatusText  string     `json:"statusText"`
+	HTTPVersion string     `json:"httpVersion"`
+	Cookies     []harNV    `json:"cookies"`
+	Headers     []harNV    `json:"headers"`
+	Content     harContent `json:"content"`
+	RedirectURL string     `json:"redirectURL"`
+	HeadersSize int        `json:"headersSize"`
+	BodySize    int        `json:"bodySize"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+
+	// custom fields carry the spec tagging
+	ID          string `json:"_id"`
+	Spec        string `json:"_spec,omitempty"`
+	Env         string `json:"_env,omitempty"`
+	OperationID string `json:"_operationId,omitempty"`
+}
+
+type harLog struct {
+	Log struct {
+		Version string `json:"version"`
+		Creator struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+func harHeaders(h http.Header) []harNV {
+	out := []harNV{}
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range h[k] {
+			out = append(out, harNV{k, v})
+		}
+	}
+	return out
+}
+
+// harText returns the body as HAR text, base64-encoded when not UTF-8.
+func harText(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+// toHAR converts the entries to a HAR 1.2 log.
+func toHAR(entries []*RecordedEntry) harLog {
+	var h harLog
+	h.Log.Version = "1.2"
+	h.Log.Creator.Name = "better-docs"
+	h.Log.Creator.Version = "1.0"
+	h.Log.Entries = make([]harEntry, 0, len(entries))
+
+	for _, e := range entries {
+		ms := float64(e.Duration) / float64(time.Millisecond)
+		he := harEntry{
+			StartedDateTime: e.Time.Format(time.RFC3339Nano),
+			Time:            ms,
+			Timings:         harTimings{Wait: ms},
+			ID:              e.ID,
+			Spec:            e.Spec,
+			Env:             e.Env,
+			OperationID:     e.OperationID,
+		}
+
+		he.Request = harRequest{
+			Method:      e.Request.Method,
+			URL:         e.Request.URL,
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harNV{},
+			Headers:     harHeaders(e.Request.Headers),
+			QueryString: []harNV{},
+			HeadersSize: -1,
+			BodySize:    len(e.Request.Body),
+		}
+		if u, err := url.Parse(e.Request.URL); err == nil {
+			for k, vs := range u.Query() {
+				for _, v := range vs {
+					he.Request.QueryString = append(he.Request.QueryString, harNV{k, v})
+				}
+			}
+		}
+		if len(e.Request.Body) > 0 {
+			text, _ := harText(e.Request.Body)
+			he.Request.PostData = &harPostData{MimeType: e.Request.Headers.Get("Content-Type"), Text: text}
+		}
+
+		text, enc := harText(e.Response.Body)
+		he.Response = harResponse{
+			Status:      e.Response.Status,
+			StatusText:  http.StatusText(e.Response.Status),
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harNV{},
+			Headers:     harHeaders(e.Response.Headers),
+			Content: harContent{
+				Size:     len(e.Response.Body),
+				MimeType: e.Response.Headers.Get("Content-Type"),
+				Text:     text,
+				Encoding: enc,
+			},
+			HeadersSize: -1,
+			BodySize:    len(e.Response.Body),
+		}
+		h.Log.Entries = append(h.Log.Entries, he)
+	}
+	return h
+}
+
+// RecordingsHandler serves the recordings.
+//
+// GET /recordings[?spec=...][&format=har] lists entries, as HAR 1.2 on request.
+// GET /recordings/{id} returns one entry.
+// POST /recordings/{id}/replay re-sends the entry through proxy.
+// DELETE /recordings removes every entry.
+func (rec *Recorder) RecordingsHandler(proxy http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if rec == nil {
+			http.Error(w, "recording is disabled", http.StatusNotFound)
+			return
+		}
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/recordings"), "/")
+		id, action, _ := strings.Cut(rest, "/")
+
+		switch {
+		case id == "" && r.Method == http.MethodGet:
+			entries, err := rec.Entries(r.URL.Query().Get("spec"))
+			if err != nil {
+				http.Error(w, "failed to list recordings: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if r.URL.Query().Get("format") == "har" {
+				w.Header().Set("Content-Disposition", `attachment; filename="recordings.har"`)
+				writeJSON(w, http.StatusOK, toHAR(entries))
+				return
+			}
+			writeJSON(w, http.StatusOK, entries)
+
+		case id == "" && r.Method == http.MethodDelete:
+			files, _ := filepath.Glob(filepath.Join(rec.Dir, "*.json"))
+			for _, f := range files {
+				_ = os.Remove(f)
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		case id != "" && action == "" && r.Method == http.MethodGet:
+			e, err := rec.load(id)
+			if err != nil {
+				http.Error(w, "recording not found", http.StatusNotFound)
+				return
+			}
+			writeJSON(w, http.StatusOK, e)
+
+		case id != "" && action == "replay" && r.Method == http.MethodPost:
+			e, err := rec.load(id)
+			if err != nil {
+				http.Error(w, "recording not found", http.StatusNotFound)
+				return
+			}
+			q := url.Values{"url": {e.Request.URL}}
+			if e.Env != "" {
+				q.Set(envQueryParam, e.Env)
+			}
+			req, err := http.NewRequestWithContext(r.Context(), e.Request.Method, "/api?"+q.Encode(), bytes.NewReader(e.Request.Body))
+			if err != nil {
+				http.Error(w, "invalid recording: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			req.Header = e.Request.Headers.Clone()
+			// the caller's session still applies to the replayed call
+			for _, c := range r.Cookies() {
+				req.AddCookie(c)
+			}
+			w.Header().Set(replayHeader, e.ID)
+			proxy(w, req)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package route
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	var bodies []string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"ok":true}`)
+	}))
+	defer backend.Close()
+
+	specs := []Spec{{Name: "pets", ProxyBase: backend.URL}}
+	upstreams, err := buildUpstreams(specs)
+	require.NoError(t, err)
+	guard, err := NewGuard(GuardConfig{}, AllowedHosts(specs, nil))
+	require.NoError(t, err)
+	recorder, err := NewRecorder(t.TempDir(), nil, nil, NewDocuments(specs))
+	require.NoError(t, err)
+	proxy := NewProxyService(upstreams, nil, http.DefaultClient, guard, nil, nil, nil, recorder).ProxyHandler()
+	h := recorder.RecordingsHandler(proxy)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/pets/pets?kind=dog", strings.NewReader(`{"name":"rex"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	proxy(httptest.NewRecorder(), req)
+
+	entries, err := recorder.Entries("pets")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	e := entries[0]
+	require.Equal(t, `{"name":"rex"}`, string(e.Request.Body))
+	require.Equal(t, `{"ok":true}`, string(e.Response.Body))
+	require.Empty(t, e.Request.Headers.Get("Authorization"))
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/recordings?format=har", nil))
+	var har struct {
+		Log struct {
+			Version string `json:"version"`
+			Entries []struct {
+				Request struct {
+					Method      string  `json:"method"`
+					QueryString []harNV `json:"queryString"`
+				} `json:"request"`
+				Response struct {
+					Status int `json:"status"`
+				} `json:"response"`
+				Spec string `json:"_spec"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &har))
+	require.Equal(t, "1.2", har.Log.Version)
+	require.Len(t, har.Log.Entries, 1)
+	require.Equal(t, http.MethodPost, har.Log.Entries[0].Request.Method)
+	require.Equal(t, []harNV{{"kind", "dog"}}, har.Log.Entries[0].Request.QueryString)
+	require.Equal(t, "pets", har.Log.Entries[0].Spec)
+
+	rec = httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/recordings/"+e.ID+"/replay", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, e.ID, rec.Header().Get(replayHeader))
+	require.Equal(t, []string{`{"name":"rex"}`, `{"name":"rex"}`}, bodies)
+}
@@ -17,16 +17,20 @@ func IndexHandler(file string) http.HandlerFunc {
 func RegisterRoutes(
 	mux *http.ServeMux,
 	specs []Spec,
-	proxyMap map[string]string,
-	client *http.Client,
+	proxySvc *ProxyService,
+	corsOrigins []string,
 	staticDir, indexFile string,
 	searchSvc *SearchService,
 	actionSvc *ActionService,
+	changesSvc *ChangesService,
+	oauthSvc *OAuthService,
+	mockSvc *MockService,
 ) {
 	mux.HandleFunc("/api/specs", SpecsHandler(specs))
 	mux.HandleFunc("/api/specs/", SpecByIDHandler(specs))
+	mux.HandleFunc("/environments/", EnvironmentsHandler(proxySvc.Upstreams))
 
-	proxy := WithCORS(ProxyHandler(proxyMap, client))
+	proxy := WithCORS(corsOrigins, proxySvc.ProxyHandler())
 	mux.HandleFunc("/api", proxy)
 	mux.HandleFunc("/api/", proxy)
 
@@ -35,5 +39,25 @@ func RegisterRoutes(
 
 	mux.Handle("/search", searchSvc.SearchHandler())
 	mux.Handle("/raSearch", searchSvc.RaSearchHandler())
+	mux.Handle("/raSearch/batch", searchSvc.RaBatchHandler())
+	mux.Handle("/raSearch/har", searchSvc.HarHandler())
+	mux.Handle("/export", searchSvc.ExportHandler())
 	mux.Handle("/action", actionSvc.ActionHandler())
+	mux.Handle("/changes", changesSvc.ChangesHandler())
+
+	mux.Handle("/validation", proxySvc.Validator.ValidationHandler())
+	mux.Handle("/validation/", proxySvc.Validator.ValidationHandler())
+
+	mux.Handle("/upstreams", UpstreamsHandler(proxySvc.Upstreams))
+	mux.Handle("/upstreams/", UpstreamsHandler(proxySvc.Upstreams))
+	mux.Handle("/recordings", proxySvc.Recorder.RecordingsHandler(proxySvc.ProxyHandler()))
+	mux.Handle("/recordings/", proxySvc.Recorder.RecordingsHandler(proxySvc.ProxyHandler()))
+	mux.Handle("/faults", proxySvc.Faults.FaultsHandler())
+	mux.Handle("/faults/", proxySvc.Faults.FaultsHandler())
+	mux.Handle("/mock/", WithCORS(corsOrigins, mockSvc.MockHandler()))
+	mux.Handle("/mock-state/", mockSvc.MockStateHandler())
+
+	mux.Handle("/oauth/start", oauthSvc.StartHandler())
+	mux.Handle("/oauth/callback", oauthSvc.CallbackHandler())
+	mux.Handle("/oauth/session", oauthSvc.SessionHandler())
 }
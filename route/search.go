@@ -1,12 +1,14 @@
 package route
 
 import (
-	"bufio"
-	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
+	"mime"
 	"net/http"
+	"net/url"
+	"regexp"
 	"strconv"
 
 	"better-docs/indexing"
@@ -17,6 +19,8 @@ import (
 type SearchService struct {
 	Registry indexing.Registry
 	Index    bleve.Index
+	// LogPrefix overrides parser.DefaultLogPrefix for RestAssured lookups.
+	LogPrefix *regexp.Regexp
 }
 
 func NewSearchService(registry indexing.Registry, idx bleve.Index) *SearchService {
@@ -63,15 +67,58 @@ func (s *SearchService) SearchHandler() http.HandlerFunc {
 	}
 }
 
-// RaSearchHandler RestAssured log lookups.
+// logPrefix returns the RestAssured prefix pattern from the ?prefix= query
+// parameter, falling back to def. Nil means the parser's default.
+func logPrefix(r *http.Request, def *regexp.Regexp) (*regexp.Regexp, error) {
+	if p := r.URL.Query().Get("prefix"); p != "" {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prefix pattern: %w", err)
+		}
+		return re, nil
+	}
+	return def, nil
+}
+
+// detectFormat returns the format input is logged in. RestAssured logs get
+// the prefix pattern from logPrefix.
+func detectFormat(r *http.Request, input string, def *regexp.Regexp) (parser.Format, error) {
+	format := parser.Detect(input)
+	if _, ok := format.(parser.RestAssuredFormat); ok {
+		prefix, err := logPrefix(r, def)
+		if err != nil {
+			return nil, err
+		}
+		format = parser.RestAssuredFormat{Prefix: prefix}
+	}
+	return format, nil
+}
+
+// logScanner returns a scanner over body using logPrefix.
+func (s *SearchService) logScanner(r *http.Request, body io.Reader) (*parser.LogScanner, error) {
+	re, err := logPrefix(r, s.LogPrefix)
+	if err != nil {
+		return nil, err
+	}
+	sc := parser.NewLogScanner(body)
+	if re != nil {
+		sc.SetPrefix(re)
+	}
+	return sc, nil
+}
+
+// RaSearchHandler looks up the operation of a logged request. The format is
+// detected: RestAssured, curl, MockMvc, OkHttp or raw HTTP.
 func (s *SearchService) RaSearchHandler() http.HandlerFunc {
 
 	type ParsedRequest = parser.ParsedRequest
 
 	type Response struct {
-		SpecName    string        `json:"specName"`
-		OperationId string        `json:"operationId"`
-		ParsedInfo  ParsedRequest `json:"parsedInfo"`
+		Format         string                 `json:"format"`
+		SpecName       string                 `json:"specName"`
+		OperationId    string                 `json:"operationId"`
+		ParsedInfo     ParsedRequest          `json:"parsedInfo"`
+		ParsedResponse *parser.ParsedResponse `json:"parsedResponse,omitempty"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -81,7 +128,12 @@ func (s *SearchService) RaSearchHandler() http.HandlerFunc {
 			return
 		}
 
-		pr, err := parser.ParseLog(bufio.NewReader(bytes.NewReader(data)))
+		format, err := detectFormat(r, string(data), s.LogPrefix)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		pr, pres, err := format.Parse(string(data))
 		if err != nil {
 			http.Error(w, "parse error: "+err.Error(), http.StatusBadRequest)
 			return
@@ -96,15 +148,18 @@ func (s *SearchService) RaSearchHandler() http.HandlerFunc {
 		}
 
 		pr.PathParams = pathParams
+		pr.URI = indexing.ResolveURL(s.Registry, specName, pr.URI)
 
 		log.Printf("Path Params: %v", pathParams)
 
 		w.Header().Set("Content-Type", "application/json")
 
 		response := Response{
-			SpecName:    specName,
-			OperationId: opID,
-			ParsedInfo:  pr,
+			Format:         format.Name(),
+			SpecName:       specName,
+			OperationId:    opID,
+			ParsedInfo:     pr,
+			ParsedResponse: pres,
 		}
 
 		if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -112,3 +167,166 @@ func (s *SearchService) RaSearchHandler() http.HandlerFunc {
 		}
 	}
 }
+
+// RaBatchHandler maps every RestAssured block of a log, e.g. a failing CI
+// run's console output, to its operation in one call. Blocks that fail to
+// parse or match are reported with an error rather than failing the batch.
+//
+// POST /raSearch/batch
+func (s *SearchService) RaBatchHandler() http.HandlerFunc {
+	type Result struct {
+		Index          int                    `json:"index"`
+		StartLine      int                    `json:"startLine"`
+		EndLine        int                    `json:"endLine"`
+		SpecName       string                 `json:"specName,omitempty"`
+		OperationId    string                 `json:"operationId,omitempty"`
+		ParsedInfo     parser.ParsedRequest   `json:"parsedInfo"`
+		ParsedResponse *parser.ParsedResponse `json:"parsedResponse,omitempty"`
+		Error          string                 `json:"error,omitempty"`
+	}
+
+	type Response struct {
+		Total   int      `json:"total"`
+		Matched int      `json:"matched"`
+		Results []Result `json:"results"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		sc, err := s.logScanner(r, r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp := Response{Results: []Result{}}
+		for sc.Next() {
+			ex := sc.Exchange()
+			res := Result{
+				Index:          len(resp.Results),
+				StartLine:      ex.StartLine,
+				EndLine:        ex.EndLine,
+				ParsedInfo:     ex.Request,
+				ParsedResponse: ex.Response,
+			}
+			if ex.Err != nil {
+				res.Error = "parse error: " + ex.Err.Error()
+			} else if specName, opID, pathParams, err := indexing.FindOperation(s.Index, s.Registry, ex.Request.Method, ex.Request.URI); err != nil {
+				res.Error = "no match: " + err.Error()
+			} else {
+				res.SpecName, res.OperationId = specName, opID
+				res.ParsedInfo.PathParams = pathParams
+				resp.Matched++
+			}
+			resp.Results = append(resp.Results, res)
+		}
+		if err := sc.Err(); err != nil {
+			http.Error(w, "failed to read body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp.Total = len(resp.Results)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, "failed to write response: "+err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+// HarHandler matches every entry of an uploaded HAR, e.g. a browser DevTools
+// export, to its spec and operation. Entries to a spec's host that match no
+// operation are flagged as undocumented; entries to other hosts are reported
+// as unknown. The HAR is the raw request body or the "file" field of a
+// multipart upload.
+//
+// POST /raSearch/har
+func (s *SearchService) HarHandler() http.HandlerFunc {
+	type Result struct {
+		Index          int                    `json:"index"`
+		Method         string                 `json:"method"`
+		URL            string                 `json:"url"`
+		Status         string                 `json:"status"` // matched, undocumented, unknown-host or invalid
+		SpecName       string                 `json:"specName,omitempty"`
+		OperationId    string                 `json:"operationId,omitempty"`
+		ParsedInfo     parser.ParsedRequest   `json:"parsedInfo"`
+		ParsedResponse *parser.ParsedResponse `json:"parsedResponse,omitempty"`
+		Error          string                 `json:"error,omitempty"`
+	}
+
+	type Response struct {
+		Total        int      `json:"total"`
+		Matched      int      `json:"matched"`
+		Undocumented int      `json:"undocumented"`
+		Results      []Result `json:"results"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var body io.Reader = r.Body
+		if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "multipart/form-data" {
+			f, _, err := r.FormFile("file")
+			if err != nil {
+				http.Error(w, "missing file field: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			defer f.Close()
+			body = f
+		}
+
+		entries, err := parser.ReadHAR(body)
+		if err != nil {
+			http.Error(w, "parse error: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		resp := Response{Total: len(entries), Results: make([]Result, 0, len(entries))}
+		for i, ex := range entries {
+			res := Result{
+				Index:          i,
+				Method:         ex.Request.Method,
+				URL:            ex.Request.URI,
+				ParsedInfo:     ex.Request,
+				ParsedResponse: ex.Response,
+			}
+			u, err := url.Parse(ex.Request.URI)
+			if ex.Err != nil {
+				err = ex.Err
+			}
+			var meta *indexing.SpecIndex
+			if err == nil {
+				meta = s.Registry.ForURL(u)
+			}
+			switch {
+			case err != nil:
+				res.Status, res.Error = "invalid", "parse error: "+err.Error()
+			case meta == nil:
+				res.Status = "unknown-host"
+			default:
+				specName, opID, pathParams, err := indexing.FindOperation(s.Index, s.Registry, ex.Request.Method, ex.Request.URI)
+				if err != nil {
+					res.Status, res.Error = "undocumented", err.Error()
+					res.SpecName = meta.SpecName
+					resp.Undocumented++
+					break
+				}
+				res.Status = "matched"
+				res.SpecName, res.OperationId = specName, opID
+				res.ParsedInfo.PathParams = pathParams
+				resp.Matched++
+			}
+			resp.Results = append(resp.Results, res)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, "failed to write response: "+err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package route
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const sessionCookie = "better_docs_session"
+
+// sessionToken is an OAuth2 token obtained for one spec in one browser session.
+type sessionToken struct {
+	AccessToken  string
+	RefreshToken string
+	TokenType    string
+	Expires      time.Time
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+}
+
+// SessionStore keeps per-session, per-spec tokens in memory so the proxy can
+// authenticate try-it calls without the browser ever holding the token.
+type SessionStore struct {
+	mu     sync.Mutex
+	tokens map[string]map[string]*sessionToken
+	client *http.Client
+}
+
+func NewSessionStore(client *http.Client) *SessionStore {
+	return &SessionStore{
+		tokens: make(map[string]map[string]*sessionToken),
+		client: client,
+	}
+}
+
+func randomToken(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// SessionID returns the caller's session id, issuing a cookie when absent.
+func SessionID(w http.ResponseWriter, r *http.Request) string {
+	if c, err := r.Cookie(sessionCookie); err == nil && c.Value != "" {
+		return c.Value
+	}
+	id := randomToken(32)
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    id,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Secure:   r.TLS != nil,
+	})
+	return id
+}
+
+func requestSessionID(r *http.Request) string {
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+func (s *SessionStore) Put(session, spec string, tok *sessionToken) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.tokens[session]
+	if !ok {
+		m = make(map[string]*sessionToken)
+		s.tokens[session] = m
+	}
+	m[strings.ToLower(spec)] = tok
+}
+
+func (s *SessionStore) Delete(session, spec string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens[session], strings.ToLower(spec))
+}
+
+func (s *SessionStore) get(session, spec string) *sessionToken {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokens[session][strings.ToLower(spec)]
+}
+
+// Token returns a valid access token for the session and spec, refreshing it
+// when it is about to expire and a refresh token is available.
+func (s *SessionStore) Token(ctx context.Context, session, spec string) (string, bool) {
+	if s == nil || session == "" {
+		return "", false
+	}
+	tok := s.get(session, spec)
+	if tok == nil {
+		return "", false
+	}
+	if tok.Expires.IsZero() || time.Now().Add(tokenRefreshSkew).Before(tok.Expires) {
+		return tok.AccessToken, true
+	}
+	if tok.RefreshToken == "" {
+		s.Delete(session, spec)
+		return "", false
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", tok.RefreshToken)
+	form.Set("client_id", tok.ClientID)
+	if tok.ClientSecret != "" {
+		form.Set("client_secret", tok.ClientSecret)
+	}
+	tr, err := requestToken(ctx, s.client, tok.TokenURL, form)
+	if err != nil {
+		s.Delete(session, spec)
+		return "", false
+	}
+	next := *tok
+	next.AccessToken = tr.AccessToken
+	next.TokenType = tr.TokenType
+	next.Expires = tr.expiry()
+	if tr.RefreshToken != "" {
+		next.RefreshToken = tr.RefreshToken
+	}
+	s.Put(session, spec, &next)
+	return next.AccessToken, true
+}
+
+// stripSessionCookie removes our session cookie before a request goes upstream.
+func stripSessionCookie(h http.Header) {
+	cookies := h.Values("Cookie")
+	if len(cookies) == 0 {
+		return
+	}
+	h.Del("Cookie")
+	for _, line := range cookies {
+		var keep []string
+		for _, part := range strings.Split(line, ";") {
+			if name, _, _ := strings.Cut(strings.TrimSpace(part), "="); name == sessionCookie {
+				continue
+			}
+			if p := strings.TrimSpace(part); p != "" {
+				keep = append(keep, p)
+			}
+		}
+		if len(keep) > 0 {
+			h.Add("Cookie", strings.Join(keep, "; "))
+		}
+	}
+}
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
+
+	"better-docs/indexing"
 )
 
 type Spec struct {
@@ -15,9 +18,20 @@ type Spec struct {
 	File        string `json:"file"`
 	URL         string `json:"url"`
 	ProxyBase   string `json:"proxyBase"`
+
+	Headers            map[string]string      `json:"headers,omitempty"`
+	Auth               *AuthConfig            `json:"auth,omitempty"`
+	OAuthClients       map[string]OAuthClient `json:"oauthClients,omitempty"`
+	Environments       []Environment          `json:"environments,omitempty"`
+	DefaultEnvironment string                 `json:"defaultEnvironment,omitempty"`
+	TLS                *TLSConfig             `json:"tls,omitempty"`
+	OutboundProxy      *ProxyConfig           `json:"outboundProxy,omitempty"`
+	HeaderRules        *HeaderRules           `json:"headerRules,omitempty"`
+	ProxyBases         []string               `json:"proxyBases,omitempty"` // pooled with ProxyBase for failover
+	HealthCheck        *HealthCheck           `json:"healthCheck,omitempty"`
 }
 
-func LoadSpecs(path string) ([]Spec, map[string]string, error) {
+func LoadSpecs(path string) ([]Spec, *Upstreams, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, nil, fmt.Errorf("open specs file: %w", err)
@@ -28,17 +42,67 @@ func LoadSpecs(path string) ([]Spec, map[string]string, error) {
 	if err := json.NewDecoder(f).Decode(&specs); err != nil {
 		return nil, nil, fmt.Errorf("decode specs: %w", err)
 	}
-	pm := make(map[string]string, len(specs))
+	upstreams, err := buildUpstreams(specs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return specs, upstreams, nil
+}
+
+// SpecHosts maps every upstream host known for a spec (its proxyBase, its
+// environments' proxyBase and its declared servers) to the lower-cased spec name.
+func SpecHosts(specs []Spec, reg indexing.Registry) map[string]string {
+	hosts := make(map[string]string)
+	add := func(raw, name string) {
+		if u, err := url.Parse(raw); err == nil && u.Host != "" {
+			hosts[strings.ToLower(u.Host)] = name
+		}
+	}
+	for host, meta := range reg {
+		name := strings.ToLower(meta.SpecName)
+		hosts[strings.ToLower(host)] = name
+		for _, sv := range meta.Servers {
+			add(sv, name)
+		}
+	}
 	for _, s := range specs {
-		pm[strings.ToLower(s.Name)] = strings.TrimRight(s.ProxyBase, "/")
+		add(s.ProxyBase, strings.ToLower(s.Name))
+		for _, b := range s.ProxyBases {
+			add(b, strings.ToLower(s.Name))
+		}
+		for _, e := range s.Environments {
+			add(e.ProxyBase, strings.ToLower(s.Name))
+			for _, b := range e.ProxyBases {
+				add(b, strings.ToLower(s.Name))
+			}
+		}
 	}
-	return specs, pm, nil
+	return hosts
+}
+
+// publicSpec is what SpecsHandler exposes of a Spec. Auth, OAuth clients,
+// headers, TLS files and proxy URLs stay server-side: they carry secrets
+// and the endpoint is reachable cross-origin.
+type publicSpec struct {
+	Name               string   `json:"name"`
+	DisplayName        string   `json:"displayName"`
+	Environments       []string `json:"environments,omitempty"`
+	DefaultEnvironment string   `json:"defaultEnvironment,omitempty"`
 }
 
 func SpecsHandler(specs []Spec) http.HandlerFunc {
+	out := make([]publicSpec, 0, len(specs))
+	for _, s := range specs {
+		ps := publicSpec{Name: s.Name, DisplayName: s.DisplayName, DefaultEnvironment: s.DefaultEnvironment}
+		for _, e := range s.Environments {
+			ps.Environments = append(ps.Environments, e.Name)
+		}
+		out = append(out, ps)
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(specs); err != nil {
+		if err := json.NewEncoder(w).Encode(out); err != nil {
 			log.Printf("encode specs: %v", err)
 		}
 	}
@@ -0,0 +1,111 @@
+package route
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// isUpgrade reports whether the client asks to switch protocols, e.g. to a
+// WebSocket.
+func isUpgrade(h http.Header) bool {
+	for _, v := range h.Values("Connection") {
+		for _, tok := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(tok), "upgrade") {
+				return h.Get("Upgrade") != ""
+			}
+		}
+	}
+	return false
+}
+
+// wantsStream reports whether the call is expected to stay open: protocol
+// upgrades and server-sent event subscriptions.
+func wantsStream(r *http.Request) bool {
+	return isUpgrade(r.Header) || strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// isEventStream reports whether resp is a server-sent event stream, which
+// must never be buffered.
+func isEventStream(resp *http.Response) bool {
+	mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mt == "text/event-stream"
+}
+
+// clientFor returns the client for req. Long-lived calls get a copy without
+// the overall timeout, which would otherwise cut the stream off; they end
+// when either side goes away.
+func (s *ProxyService) clientFor(r *http.Request) *http.Client {
+	if !wantsStream(r) || s.Client.Timeout == 0 {
+		return s.Client
+	}
+	c := *s.Client
+	c.Timeout = 0
+	return &c
+}
+
+// copyFlushing copies body to w, flushing after every read so event streams
+// and chunked responses reach the client as they arrive.
+func copyFlushing(w http.ResponseWriter, body io.Reader) error {
+	rc := http.NewResponseController(w)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			if ferr := rc.Flush(); ferr != nil && !errors.Is(ferr, http.ErrNotSupported) {
+				return ferr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// proxyUpgrade completes a 101 Switching Protocols response by hijacking the
+// client connection and piping bytes both ways until either side closes.
+func proxyUpgrade(w http.ResponseWriter, resp *http.Response) error {
+	backConn, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		return fmt.Errorf("upstream switched protocols without a writable body")
+	}
+	defer backConn.Close()
+
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return fmt.Errorf("hijack client connection: %w", err)
+	}
+	defer conn.Close()
+
+	fmt.Fprintf(brw, "HTTP/1.1 %s\r\n", resp.Status)
+	if err := resp.Header.Write(brw); err != nil {
+		return err
+	}
+	if _, err := brw.WriteString("\r\n"); err != nil {
+		return err
+	}
+	if err := brw.Flush(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 2)
+	go func() {
+		// brw.Reader may already hold bytes the client sent after the handshake
+		_, err := io.Copy(backConn, brw)
+		done <- err
+	}()
+	go func() {
+		_, err := io.Copy(conn, backConn)
+		done <- err
+	}()
+	return <-done
+}
@@ -0,0 +1,98 @@
+package route
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func streamingProxy(t *testing.T, backend *httptest.Server) *httptest.Server {
+	specs := []Spec{{Name: "events", ProxyBase: backend.URL}}
+	upstreams, err := buildUpstreams(specs)
+	require.NoError(t, err)
+	guard, err := NewGuard(GuardConfig{}, AllowedHosts(specs, nil))
+	require.NoError(t, err)
+	client := &http.Client{Timeout: 200 * time.Millisecond}
+	proxy := httptest.NewServer(NewProxyService(upstreams, nil, client, guard, nil, nil, nil, nil).ProxyHandler())
+	t.Cleanup(proxy.Close)
+	return proxy
+}
+
+func TestProxyFlushesEventStream(t *testing.T) {
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: one\n\n")
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		_, _ = io.WriteString(w, "data: two\n\n")
+	}))
+	defer backend.Close()
+	proxy := streamingProxy(t, backend)
+
+	req, _ := http.NewRequest(http.MethodGet, proxy.URL+"/api/events/stream", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	br := bufio.NewReader(resp.Body)
+	line, err := br.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "data: one\n", line)
+
+	// outlives the proxy client's timeout
+	time.Sleep(300 * time.Millisecond)
+	close(release)
+	rest, err := io.ReadAll(br)
+	require.NoError(t, err)
+	require.Equal(t, "\ndata: two\n\n", string(rest))
+}
+
+func TestProxyUpgradesConnection(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isUpgrade(r.Header) || r.Header.Get("Upgrade") != "echo" {
+			http.Error(w, "upgrade required", http.StatusUpgradeRequired)
+			return
+		}
+		conn, brw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+		_ = brw.Flush()
+		_, _ = io.Copy(conn, brw)
+	}))
+	defer backend.Close()
+	proxy := streamingProxy(t, backend)
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(proxy.URL, "http://"))
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = io.WriteString(conn, "GET /api/events/socket HTTP/1.1\r\nHost: proxy\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+	require.NoError(t, err)
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	_, err = io.WriteString(conn, "ping")
+	require.NoError(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(br, buf)
+	require.NoError(t, err)
+	require.Equal(t, "ping", string(buf))
+}
@@ -0,0 +1,135 @@
+package route
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// TLSConfig customises TLS towards a spec's upstream: a private CA bundle,
+// a client certificate for mTLS, an SNI override, or no verification at all.
+type TLSConfig struct {
+	CAFile             string `json:"caFile,omitempty"`
+	CertFile           string `json:"certFile,omitempty"`
+	KeyFile            string `json:"keyFile,omitempty"`
+	ServerName         string `json:"serverName,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+}
+
+func (c *TLSConfig) clientConfig() (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA bundle %q has no certificates", c.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		if c.CertFile == "" || c.KeyFile == "" {
+			return nil, fmt.Errorf("certFile and keyFile must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// SpecTransport picks the transport for an upstream call by target host, so
+// each spec (or environment) gets its own TLS and jump-proxy settings. Hosts
+// without settings use the base transport.
+type SpecTransport struct {
+	base  *http.Transport
+	hosts map[string]*http.Transport
+}
+
+// NewSpecTransport derives a transport from base for every spec or
+// environment that declares TLS or outbound proxy settings. hosts maps
+// upstream hosts to spec names, as returned by SpecHosts. Environments
+// inherit whatever they do not set themselves from their spec.
+func NewSpecTransport(base *http.Transport, specs []Spec, hosts map[string]string) (*SpecTransport, error) {
+	t := &SpecTransport{base: base, hosts: make(map[string]*http.Transport)}
+	derive := func(spec string, tc *TLSConfig, pc *ProxyConfig) (*http.Transport, error) {
+		tr := base.Clone()
+		if tc != nil {
+			cfg, err := tc.clientConfig()
+			if err != nil {
+				return nil, fmt.Errorf("tls for %s: %w", spec, err)
+			}
+			tr.TLSClientConfig = cfg
+		}
+		if pc != nil {
+			fn, err := pc.proxyFunc()
+			if err != nil {
+				return nil, fmt.Errorf("outbound proxy for %s: %w", spec, err)
+			}
+			tr.Proxy = fn
+		}
+		return tr, nil
+	}
+
+	for _, s := range specs {
+		name := strings.ToLower(s.Name)
+		if s.TLS != nil || s.OutboundProxy != nil {
+			tr, err := derive(s.Name, s.TLS, s.OutboundProxy)
+			if err != nil {
+				return nil, err
+			}
+			for host, spec := range hosts {
+				if spec == name {
+					t.hosts[host] = tr
+				}
+			}
+		}
+		// an environment's own settings win for the hosts of its pool
+		for _, e := range s.Environments {
+			if e.TLS == nil && e.OutboundProxy == nil {
+				continue
+			}
+			tc, pc := s.TLS, s.OutboundProxy
+			if e.TLS != nil {
+				tc = e.TLS
+			}
+			if e.OutboundProxy != nil {
+				pc = e.OutboundProxy
+			}
+			tr, err := derive(s.Name+" ("+e.Name+")", tc, pc)
+			if err != nil {
+				return nil, err
+			}
+			for _, base := range append([]string{e.ProxyBase}, e.ProxyBases...) {
+				if u, err := url.Parse(base); err == nil && u.Host != "" {
+					t.hosts[strings.ToLower(u.Host)] = tr
+				}
+			}
+		}
+	}
+	return t, nil
+}
+
+func (t *SpecTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if tr, ok := t.hosts[strings.ToLower(req.URL.Host)]; ok {
+		return tr.RoundTrip(req)
+	}
+	if tr, ok := t.hosts[strings.ToLower(req.URL.Hostname())]; ok {
+		return tr.RoundTrip(req)
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,55 @@
+package route
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecTransportTrustsSpecCA(t *testing.T) {
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "secure")
+	}))
+	defer backend.Close()
+
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: backend.Certificate().Raw})
+	require.NoError(t, os.WriteFile(ca, cert, 0o644))
+
+	host := strings.TrimPrefix(backend.URL, "https://")
+	hosts := map[string]string{host: "internal"}
+	base := http.DefaultTransport.(*http.Transport).Clone()
+
+	plain, err := NewSpecTransport(base, []Spec{{Name: "internal"}}, hosts)
+	require.NoError(t, err)
+	_, err = (&http.Client{Transport: plain}).Get(backend.URL)
+	require.Error(t, err)
+
+	trusted, err := NewSpecTransport(base, []Spec{{Name: "internal", TLS: &TLSConfig{CAFile: ca}}}, hosts)
+	require.NoError(t, err)
+	resp, err := (&http.Client{Transport: trusted}).Get(backend.URL)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.Equal(t, "secure", string(body))
+
+	// an environment's settings apply to its own host only
+	envOnly, err := NewSpecTransport(base, []Spec{{
+		Name:         "internal",
+		Environments: []Environment{{Name: "dev", ProxyBase: backend.URL, TLS: &TLSConfig{InsecureSkipVerify: true}}},
+	}}, hosts)
+	require.NoError(t, err)
+	resp, err = (&http.Client{Transport: envOnly}).Get(backend.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	_, err = NewSpecTransport(base, []Spec{{Name: "internal", TLS: &TLSConfig{CertFile: ca}}}, hosts)
+	require.Error(t, err)
+}
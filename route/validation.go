@@ -0,0 +1,247 @@
+package route
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+)
+
+const (
+	ValidateOff     = "off"
+	ValidateReport  = "report"
+	ValidateEnforce = "enforce"
+
+	// validateHeader overrides the server's validation mode for one request.
+	validateHeader       = "X-Docs-Validate"
+	validationHeader     = "X-Docs-Validation"
+	validationIDHeader   = "X-Docs-Validation-Id"
+	maxValidationReports = 200
+)
+
+// ValidationReport records contract violations for one proxied call.
+type ValidationReport struct {
+	ID          string    `json:"id"`
+	Time        time.Time `json:"time"`
+	Spec        string    `json:"spec"`
+	Env         string    `json:"env"`
+	Method      string    `json:"method"`
+	URL         string    `json:"url"`
+	OperationID string    `json:"operationId,omitempty"`
+	Status      int       `json:"status,omitempty"`
+	Request     []string  `json:"request"`
+	Response    []string  `json:"response"`
+}
+
+func (r *ValidationReport) summary() string {
+	return fmt.Sprintf("request=%d; response=%d", len(r.Request), len(r.Response))
+}
+
+// Validator checks proxied traffic against the spec with openapi3filter and
+// keeps the most recent reports for the /validation side channel.
+type Validator struct {
+	Mode string
+	Docs *Documents
+
+	mu      sync.Mutex
+	seq     int
+	reports []*ValidationReport
+}
+
+func NewValidator(mode string, docs *Documents) (*Validator, error) {
+	switch mode {
+	case "", ValidateOff:
+		mode = ValidateOff
+	case ValidateReport, ValidateEnforce:
+	default:
+		return nil, fmt.Errorf("unknown validation mode %q", mode)
+	}
+	return &Validator{Mode: mode, Docs: docs}, nil
+}
+
+// modeFor returns the effective mode, honouring a per-request override, and
+// removes the override header so it is not sent upstream.
+func (v *Validator) modeFor(r *http.Request) string {
+	h := strings.ToLower(r.Header.Get(validateHeader))
+	r.Header.Del(validateHeader)
+	if v == nil {
+		return ValidateOff
+	}
+	if h != "" {
+		switch h {
+		case ValidateOff, ValidateReport, ValidateEnforce:
+			return h
+		case "1", "true", "on":
+			return ValidateReport
+		}
+	}
+	return v.Mode
+}
+
+func validationOptions() *openapi3filter.Options {
+	return &openapi3filter.Options{
+		MultiError:            true,
+		IncludeResponseStatus: true,
+		SkipSettingDefaults:   true,
+		AuthenticationFunc:    openapi3filter.NoopAuthenticationFunc,
+	}
+}
+
+// errorMessages flattens an openapi3filter error into readable lines.
+func errorMessages(err error) []string {
+	if err == nil {
+		return nil
+	}
+	var me openapi3.MultiError
+	if errors.As(err, &me) {
+		var out []string
+		for _, e := range me {
+			out = append(out, errorMessages(e)...)
+		}
+		return out
+	}
+	return []string{strings.ReplaceAll(err.Error(), "\n", " ")}
+}
+
+// CheckRequest validates the outgoing request. The request body is buffered
+// and restored so it can still be forwarded. The returned input is nil when
+// the operation could not be resolved and the response cannot be checked.
+func (v *Validator) CheckRequest(ctx context.Context, req *http.Request, up *Upstream) (*ValidationReport, *openapi3filter.RequestValidationInput) {
+	report := &ValidationReport{
+		Time:     time.Now().UTC(),
+		Spec:     up.Spec,
+		Env:      up.Env,
+		Method:   req.Method,
+		URL:      req.URL.String(),
+		Request:  []string{},
+		Response: []string{},
+	}
+
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			report.Request = append(report.Request, "read body: "+err.Error())
+			return report, nil
+		}
+		body = b
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+		req.ContentLength = int64(len(body))
+	}
+
+	var bases []string
+	if b, err := url.Parse(up.Base); err == nil {
+		bases = append(bases, b.Path)
+	}
+	route, params, err := v.Docs.FindRoute(up.Spec, req.Method, req.URL.Path, bases...)
+	if err != nil {
+		report.Request = append(report.Request, err.Error())
+		return report, nil
+	}
+	report.OperationID = route.Operation.OperationID
+
+	vreq := req.Clone(ctx)
+	vreq.Body = io.NopCloser(bytes.NewReader(body))
+	in := &openapi3filter.RequestValidationInput{
+		Request:    vreq,
+		PathParams: params,
+		Route:      route,
+		Options:    validationOptions(),
+	}
+	report.Request = append(report.Request, errorMessages(openapi3filter.ValidateRequest(ctx, in))...)
+	return report, in
+}
+
+// CheckResponse validates the upstream response against the operation.
+func (v *Validator) CheckResponse(ctx context.Context, report *ValidationReport, in *openapi3filter.RequestValidationInput, status int, header http.Header, body []byte) {
+	report.Status = status
+	if in == nil {
+		return
+	}
+	rin := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: in,
+		Status:                 status,
+		Header:                 header,
+		Options:                validationOptions(),
+	}
+	rin.SetBodyBytes(body)
+	report.Response = append(report.Response, errorMessages(openapi3filter.ValidateResponse(ctx, rin))...)
+}
+
+// Store keeps the report and returns its id.
+func (v *Validator) Store(report *ValidationReport) string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.seq++
+	report.ID = strconv.Itoa(v.seq)
+	v.reports = append(v.reports, report)
+	if len(v.reports) > maxValidationReports {
+		v.reports = v.reports[len(v.reports)-maxValidationReports:]
+	}
+	return report.ID
+}
+
+// setHeaders exposes the report on the proxied response.
+func (report *ValidationReport) setHeaders(h http.Header) {
+	h.Set(validationIDHeader, report.ID)
+	h.Set(validationHeader, report.summary())
+	h.Add("Access-Control-Expose-Headers", validationIDHeader+", "+validationHeader)
+}
+
+// ValidationHandler serves stored reports.
+//
+// GET /validation[?spec=...&violations=true] lists recent reports, newest first.
+// GET /validation/{id} returns one report.
+func (v *Validator) ValidationHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/validation"), "/")
+
+		v.mu.Lock()
+		var out interface{}
+		if id != "" {
+			for _, rep := range v.reports {
+				if rep.ID == id {
+					out = rep
+				}
+			}
+		} else {
+			spec := strings.ToLower(r.URL.Query().Get("spec"))
+			onlyViolations := r.URL.Query().Get("violations") == "true"
+			list := make([]*ValidationReport, 0, len(v.reports))
+			for i := len(v.reports) - 1; i >= 0; i-- {
+				rep := v.reports[i]
+				if spec != "" && rep.Spec != spec {
+					continue
+				}
+				if onlyViolations && len(rep.Request)+len(rep.Response) == 0 {
+					continue
+				}
+				list = append(list, rep)
+			}
+			out = list
+		}
+		v.mu.Unlock()
+
+		if out == nil {
+			http.Error(w, "report not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(out); err != nil {
+			http.Error(w, "failed to write response: "+err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
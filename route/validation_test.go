@@ -0,0 +1,137 @@
+package route
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const validationSpec = `{
+  "openapi": "3.0.0",
+  "info": { "title": "Pets", "version": "1.0.0" },
+  "servers": [{ "url": "http://pets.test/v1" }],
+  "paths": {
+    "/pets/{id}": {
+      "put": {
+        "operationId": "updatePet",
+        "parameters": [{ "name": "id", "in": "path", "required": true, "schema": { "type": "integer" } }],
+        "requestBody": { "required": true, "content": { "application/json": { "schema": {
+          "type": "object", "required": ["name"], "properties": { "name": { "type": "string" } }
+        } } } },
+        "responses": { "200": { "description": "OK", "content": { "application/json": { "schema": {
+          "type": "object", "required": ["id"], "properties": { "id": { "type": "integer" } }
+        } } } } }
+      }
+    }
+  }
+}`
+
+func TestProxyValidation(t *testing.T) {
+	var upstreamHeaders []http.Header
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamHeaders = append(upstreamHeaders, r.Header.Clone())
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id":"not-a-number"}`)
+	}))
+	defer backend.Close()
+
+	file := filepath.Join(t.TempDir(), "pets.json")
+	require.NoError(t, os.WriteFile(file, []byte(validationSpec), 0o644))
+	specs := []Spec{{Name: "pets", File: file, ProxyBase: backend.URL + "/v1"}}
+
+	upstreams, err := buildUpstreams(specs)
+	require.NoError(t, err)
+	guard, err := NewGuard(GuardConfig{}, nil)
+	require.NoError(t, err)
+	validator, err := NewValidator(ValidateReport, NewDocuments(specs))
+	require.NoError(t, err)
+	h := NewProxyService(upstreams, nil, http.DefaultClient, guard, nil, validator, nil, nil).ProxyHandler()
+
+	req := httptest.NewRequest(http.MethodPut, "/api/pets/pets/7", strings.NewReader(`{"nick":"rex"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(validateHeader, ValidateReport)
+	rec := httptest.NewRecorder()
+	h(rec, req)
+	require.Len(t, upstreamHeaders, 1)
+	require.Empty(t, upstreamHeaders[0].Values(validateHeader))
+
+	// report mode still forwards the call
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, `{"id":"not-a-number"}`, rec.Body.String())
+	require.Equal(t, "request=1; response=1", rec.Header().Get(validationHeader))
+
+	rec2 := httptest.NewRecorder()
+	validator.ValidationHandler()(rec2, httptest.NewRequest(http.MethodGet, "/validation/"+rec.Header().Get(validationIDHeader), nil))
+	var report ValidationReport
+	require.NoError(t, json.Unmarshal(rec2.Body.Bytes(), &report))
+	require.Equal(t, "updatePet", report.OperationID)
+	require.Len(t, report.Request, 1)
+	require.Len(t, report.Response, 1)
+
+	// enforce mode rejects before forwarding
+	req = httptest.NewRequest(http.MethodPut, "/api/pets/pets/7", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(validateHeader, ValidateEnforce)
+	rec = httptest.NewRecorder()
+	h(rec, req)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Len(t, upstreamHeaders, 1)
+
+	// the override is stripped even with validation disabled
+	h = NewProxyService(upstreams, nil, http.DefaultClient, guard, nil, nil, nil, nil).ProxyHandler()
+	req = httptest.NewRequest(http.MethodPut, "/api/pets/pets/7", strings.NewReader(`{}`))
+	req.Header.Set(validateHeader, ValidateEnforce)
+	h(httptest.NewRecorder(), req)
+	require.Len(t, upstreamHeaders, 2)
+	require.Empty(t, upstreamHeaders[1].Values(validateHeader))
+}
+
+func TestValidationReportOmitsQueryKey(t *testing.T) {
+	var gotKey string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.URL.Query().Get("api_key")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id":7}`)
+	}))
+	defer backend.Close()
+
+	file := filepath.Join(t.TempDir(), "pets.json")
+	require.NoError(t, os.WriteFile(file, []byte(validationSpec), 0o644))
+	t.Setenv("PETS_KEY_FOR_TEST", "s3cret-key")
+	specs := []Spec{{
+		Name:      "pets",
+		File:      file,
+		ProxyBase: backend.URL + "/v1",
+		Auth:      &AuthConfig{Type: "apiKey", In: "query", Name: "api_key", Secret: "PETS_KEY_FOR_TEST"},
+	}}
+	upstreams, err := buildUpstreams(specs)
+	require.NoError(t, err)
+	secrets, err := LoadSecretStore("")
+	require.NoError(t, err)
+	require.NoError(t, upstreams.LoadCredentials(secrets, nil))
+	guard, err := NewGuard(GuardConfig{}, nil)
+	require.NoError(t, err)
+	validator, err := NewValidator(ValidateReport, NewDocuments(specs))
+	require.NoError(t, err)
+	h := NewProxyService(upstreams, nil, http.DefaultClient, guard, nil, validator, nil, nil).ProxyHandler()
+
+	req := httptest.NewRequest(http.MethodPut, "/api/pets/pets/7", strings.NewReader(`{"name":"rex"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "s3cret-key", gotKey)
+
+	rec2 := httptest.NewRecorder()
+	validator.ValidationHandler()(rec2, httptest.NewRequest(http.MethodGet, "/validation/"+rec.Header().Get(validationIDHeader), nil))
+	require.Equal(t, http.StatusOK, rec2.Code)
+	require.Contains(t, rec2.Body.String(), "/v1/pets/7")
+	require.NotContains(t, rec2.Body.String(), "s3cret-key")
+}
@@ -27,6 +27,7 @@
 
     const els = {
         specSelector: $('#spec-selector'),
+        envSelector: $('#env-selector'),
         viewer: $('#viewer-container'),
 
         // Filter modal
@@ -87,6 +88,21 @@
 
         observeTryIt(name);
         applyRaPayload();
+        await loadEnvironments(name);
+    }
+
+    async function loadEnvironments(name) {
+        els.envSelector.innerHTML = '';
+        sessionStorage.removeItem('docs-env');
+
+        const res = await fetch(`/environments/${name}`);
+        if (!res.ok) return;
+        const envs = await res.json();
+
+        envs.forEach(e => els.envSelector.append(new Option(e.name, e.name, e.default, e.default)));
+        const saved = ls.get(`docs-env:${name}`);
+        if (saved && envs.some(e => e.name === saved)) els.envSelector.value = saved;
+        sessionStorage.setItem('docs-env', els.envSelector.value);
     }
 
 
@@ -128,10 +144,14 @@
 
         injectResetButton(panel, specName, opKey, inputs, ta);
 
-        // Param (input) persistence
+        // Param (input) persistence; credentials are injected by the proxy and never stored
         inputs.forEach(input => {
             const key = input.getAttribute('aria-label');
             const storageKey = `el-param:${specName}:${opKey}:${key}`;
+            if (isSecretInput(input)) {
+                ls.del(storageKey);
+                return;
+            }
             const saved = ls.get(storageKey);
             input.value = saved ?? panel.__defaultParams[key];
             input.dispatchEvent(new Event('input', {bubbles: true}));
@@ -151,6 +171,9 @@
         }
     }
 
+    const secretLabel = /authorization|token|api[-_ ]?key|password|secret/i;
+    const isSecretInput = input => input.type === 'password' || secretLabel.test(input.getAttribute('aria-label') || '');
+
     function injectResetButton(panel, specName, opKey, inputs, ta) {
         const btnGroup = $('.sl-stack.sl-stack--horizontal', panel);
         if (!btnGroup) return;
@@ -333,6 +356,8 @@
             if (!res.ok) throw new Error(await res.text());
 
             lastRaSearchResult = await res.json();
+            const logged = lastRaSearchResult.parsedResponse;
+            if (logged) els.raResponse.value = loggedResponseText(logged);
             els.raSubmit.textContent = 'Open Spec';
             notify(`✔ Found operation “${lastRaSearchResult.operationId}” at “${lastRaSearchResult.specName}”`, 'success');
         } catch (err) {
@@ -341,6 +366,18 @@
         }
     }
 
+    // Render the response captured in the log, as /action renders a live one
+    function loggedResponseText({Proto, Status, Headers = {}, Body: b64Body}) {
+        const lines = [`Logged response:`, `${Proto} ${Status}`];
+        Object.entries(Headers).forEach(([k, vs]) => vs.forEach(v => lines.push(`${k}: ${v}`)));
+        let body = b64Body ? atob(b64Body) : '';
+        try {
+            body = JSON.stringify(JSON.parse(body), null, 2);
+        } catch {
+        }
+        return [...lines, '', body].join('\n');
+    }
+
     async function openRaSpec() {
         const {specName, operationId, parsedInfo} = lastRaSearchResult;
         const {PathParams = {}, Params = {}, Body: b64Body} = parsedInfo;
@@ -485,6 +522,11 @@
         });
 
 
+        on(els.envSelector, 'change', () => {
+            ls.set(`docs-env:${currentSpec}`, els.envSelector.value);
+            sessionStorage.setItem('docs-env', els.envSelector.value);
+        });
+
         // Filter modal
         on(els.filterBtn, 'click', openFilterModal);
         on(els.filterClose, 'click', () => els.filterModal.classList.remove('open'));
@@ -524,6 +566,18 @@
         on(els.raSubmit, 'click', handleRaSearch);
         on(els.raAction, 'click', raAction);
 
+        // OAuth popup (deliver=ui) hands the token back to fill the try-it auth input
+        on(window, 'message', e => {
+            if (e.origin !== window.location.origin || e.data?.type !== 'better-docs-oauth') return;
+            const panel = $('.TryItPanel', els.viewer);
+            if (!panel || !e.data.accessToken) return;
+            $$('input[aria-label]', panel).filter(isSecretInput).forEach(input => {
+                getValueSetter(input).call(input, e.data.accessToken);
+                input.dispatchEvent(new Event('input', {bubbles: true}));
+                input.dispatchEvent(new Event('change', {bubbles: true}));
+            });
+        });
+
         on(window, 'hashchange', () => {
             const panel = $('.TryItPanel', els.viewer);
             if (panel) {
@@ -3,6 +3,7 @@ module swagger-fetcher
 go 1.24
 
 require (
+	better-docs v0.0.0
 	github.com/spf13/cobra v1.9.1
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -11,3 +12,6 @@ require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.6 // indirect
 )
+
+// noproxy is shared with the server
+replace better-docs => ../
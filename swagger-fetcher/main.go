@@ -2,17 +2,20 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"better-docs/noproxy"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 )
@@ -29,7 +32,8 @@ var (
 	proxyPrefix string
 	namesFilter string
 
-	httpClient = &http.Client{Timeout: httpTimeout}
+	// httpClient is set up in main so all downloads share one transport.
+	httpClient *http.Client
 )
 
 var replacementParameters = []map[string]interface{}{
@@ -47,6 +51,16 @@ type Spec struct {
 	URL                   string   `json:"url"`
 	OverrideRemoveDefault bool     `json:"overrideRemoveDefault"`
 	OverrideServers       []Server `json:"overrideServers"`
+
+	OutboundProxy *ProxyConfig `json:"outboundProxy,omitempty"`
+}
+
+// ProxyConfig routes a spec's downloads through an HTTP, HTTPS or SOCKS5
+// jump proxy; "direct" bypasses any proxy from the environment. NoProxy
+// entries follow no_proxy semantics.
+type ProxyConfig struct {
+	URL     string   `json:"url"`
+	NoProxy []string `json:"noProxy,omitempty"`
 }
 
 type Server struct {
@@ -88,8 +102,41 @@ func writeSpec(dst string, spec map[string]interface{}) error {
 	return os.WriteFile(dst, out, filePerm)
 }
 
-func download(u string) ([]byte, error) {
-	resp, err := httpClient.Get(u)
+// proxyKey carries a download's *ProxyConfig in its request context.
+type proxyKey struct{}
+
+// newTransport builds the transport every download shares. The proxy is
+// chosen per request from the spec's outbound proxy, falling back to the
+// environment for specs without one.
+func newTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	fromEnv := t.Proxy
+	t.Proxy = func(req *http.Request) (*url.URL, error) {
+		pc, _ := req.Context().Value(proxyKey{}).(*ProxyConfig)
+		if pc == nil {
+			return fromEnv(req)
+		}
+		if strings.EqualFold(pc.URL, "direct") || noproxy.Match(pc.NoProxy, req.URL) {
+			return nil, nil
+		}
+		return url.Parse(pc.URL)
+	}
+	return t
+}
+
+func download(sp Spec, u string) ([]byte, error) {
+	ctx := context.Background()
+	if pc := sp.OutboundProxy; pc != nil {
+		if pu, err := url.Parse(pc.URL); !strings.EqualFold(pc.URL, "direct") && (err != nil || pu.Host == "") {
+			return nil, fmt.Errorf("invalid outbound proxy %q for %s", pc.URL, sp.Name)
+		}
+		ctx = context.WithValue(ctx, proxyKey{}, pc)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -219,7 +266,7 @@ func fetchReferencedServerURL(specs []Spec, refName string) (string, string) {
 			continue
 		}
 		refSpec := &specs[i]
-		b, err := download(refSpec.URL)
+		b, err := download(*refSpec, refSpec.URL)
 		if err != nil {
 			log.Printf("⚠️ download referenced spec %s: %v", refName, err)
 			return "", ""
@@ -295,7 +342,7 @@ func applyServerOverrides(raw map[string]interface{}, sp Spec, fullSpecs []Spec)
 func processSpec(sp Spec, allSpecs []Spec) Spec {
 	log.Printf("processing %s from %s …", sp.Name, sp.URL)
 
-	content, err := download(sp.URL)
+	content, err := download(sp, sp.URL)
 	if err != nil {
 		log.Printf("download failed: %v", err)
 		return sp
@@ -374,6 +421,7 @@ func execute() {
 }
 
 func main() {
+	httpClient = &http.Client{Timeout: httpTimeout, Transport: newTransport()}
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
 	}